@@ -84,10 +84,13 @@ func (v ASCIIPlistNode) Flatten() interface{} {
 }
 
 type asciiPlistParser struct {
-	filepath  string
-	lexer     *lexer
-	peekCount int
-	token     [2]annotatedItem
+	filepath string
+	lexer    *lexer
+	// duplicatedKeys does not stop parsing
+	// so that all of them can be reported.
+	duplicatedKeys errors.List
+	peekCount      int
+	token          [2]annotatedItem
 }
 
 func (p *asciiPlistParser) next() annotatedItem {
@@ -230,12 +233,13 @@ func (p *asciiPlistParser) parseDict(startToken annotatedItem, terminatingType i
 		p.expect(itemSemicolon)
 		key := keyValue.Value.(string)
 		if seen := seenKeys[key]; seen {
-			panic(errors.FileLineCol(
+			p.duplicatedKeys = append(p.duplicatedKeys, errors.FileLineCol(
 				p.filepath,
 				keyValue.Line,
 				keyValue.Col,
 				fmt.Sprintf("duplicated key `%v`", key),
-			))
+			).WithRule(ruleDuplicatedKey))
+			continue
 		}
 		seenKeys[key] = true
 		outValue.Keys = append(outValue.Keys, keyValue)
@@ -261,6 +265,10 @@ func (p *asciiPlistParser) parseArray(startToken annotatedItem) (out ASCIIPlistN
 		p.backup(token)
 		if !first {
 			p.expect(itemComma)
+			// Trailing comma, e.g. in project.pbxproj
+			if p.peekNonSpace().item.Type == itemParenRight {
+				continue
+			}
 		}
 		valueValue := p.parseValue()
 		if first {
@@ -339,6 +347,9 @@ func (p *asciiPlistParser) parse() (out ASCIIPlistNode, err error) {
 		out = p.parseValue()
 	}
 	p.expect(itemEOF)
+	if len(p.duplicatedKeys) > 0 {
+		err = p.duplicatedKeys
+	}
 	return
 }
 
@@ -99,6 +99,7 @@ func TestParseASCIIPlist(t *testing.T) {
 		{"/*a*/(/*a*/)/*a*/", []interface{}{}},
 		{"/*a*/(/*a*/1 /*a*/)/*a*/", []interface{}{"1"}},
 		{"/*a*/(/*a*/1 /*a*/,/*a*/2 /*a*/)/*a*/", []interface{}{"1", "2"}},
+		{"/*a*/(/*a*/1 /*a*/,/*a*/2 /*a*/,/*a*/)/*a*/", []interface{}{"1", "2"}},
 
 		// dict
 		{"", map[string]interface{}{}},
@@ -107,6 +108,7 @@ func TestParseASCIIPlist(t *testing.T) {
 		{"/*a*/ ", map[string]interface{}{}},
 		{" /*a*/", map[string]interface{}{}},
 		{" /*a*/ ", map[string]interface{}{}},
+		{"// a\n", map[string]interface{}{}},
 		{
 			"/*a*/$-_.:/ /*a*/=/*a*/a /*a*/;/*a*/",
 			map[string]interface{}{
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/iawaknahc/gogenstrings/bplist"
+	"github.com/iawaknahc/gogenstrings/xmlplist"
+)
+
+func isXMLPlist(src string) bool {
+	return strings.HasPrefix(strings.TrimSpace(src), "<?xml")
+}
+
+// readCompiledDotStrings reads .strings in build products.
+// It can be binary plist, XML plist or .strings.
+func readCompiledDotStrings(fullpath string) (entries, error) {
+	b, err := ioutil.ReadFile(fullpath)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(b, []byte(bplist.Magic)) {
+		value, err := bplist.ParseBinaryPlist(b, fullpath)
+		if err != nil {
+			return nil, err
+		}
+		return entriesFromPlistValue(value, fullpath)
+	}
+	src, _, err := decode(fullpath, b)
+	if err != nil {
+		return nil, err
+	}
+	if isXMLPlist(src) {
+		value, err := xmlplist.ParseXMLPlist(src, fullpath)
+		if err != nil {
+			return nil, err
+		}
+		return entriesFromPlistValue(value, fullpath)
+	}
+	return parseDotStrings(src, fullpath)
+}
+
+// readCompiledStringsdict is like readCompiledDotStrings
+// but reads .stringsdict.
+func readCompiledStringsdict(fullpath string) (stringsdictEntryMap, error) {
+	b, err := ioutil.ReadFile(fullpath)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(b, []byte(bplist.Magic)) {
+		value, err := bplist.ParseBinaryPlist(b, fullpath)
+		if err != nil {
+			return nil, err
+		}
+		return stringsdictFromValue(value, fullpath)
+	}
+	src, _, err := decode(fullpath, b)
+	if err != nil {
+		return nil, err
+	}
+	return parseStringsdict(src, fullpath)
+}
+
+func entriesFromPlistValue(value xmlplist.Value, filepath string) (entries, error) {
+	dict, ok := value.Value.(xmlplist.Dict)
+	if !ok {
+		return nil, fileLineColErr(filepath, value.Line, value.Col, "not in .strings format")
+	}
+	es := entries{}
+	for _, keyValue := range dict.Keys() {
+		key := keyValue.Value.(string)
+		valueValue, _ := dict.Get(key)
+		s, ok := valueValue.Value.(string)
+		if !ok {
+			return nil, fileLineColErr(filepath, valueValue.Line, valueValue.Col, "expected <string>")
+		}
+		es = append(es, entry{
+			filepath:  filepath,
+			startLine: keyValue.Line,
+			startCol:  keyValue.Col,
+			key:       key,
+			value:     s,
+		})
+	}
+	return es, nil
+}
+
+// readBuildProduct reads every .strings and .stringsdict in lprojs.
+// They are treated as the output so that
+// the output validations can be reused.
+func (p *genstringsContext) readBuildProduct() {
+	for _, lproj := range p.lprojs {
+		infos, err := ioutil.ReadDir(lproj)
+		if err != nil {
+			p.diagnostics.Add(err)
+			continue
+		}
+		for _, info := range infos {
+			if !info.Mode().IsRegular() {
+				continue
+			}
+			fullpath := lproj + "/" + info.Name()
+			ext := filepath.Ext(fullpath)
+			table := strings.TrimSuffix(info.Name(), ext)
+			switch ext {
+			case dotStringsExt:
+				es, err := readCompiledDotStrings(fullpath)
+				if err != nil {
+					p.diagnostics.Add(err)
+					continue
+				}
+				em, err := es.toEntryMap()
+				p.diagnostics.Add(err)
+				if p.outEntryMap[table] == nil {
+					p.outEntryMap[table] = make(map[string]entryMap)
+				}
+				p.outEntryMap[table][lproj] = em
+			case dotStringsdictExt:
+				em, err := readCompiledStringsdict(fullpath)
+				if err != nil {
+					p.diagnostics.Add(err)
+					continue
+				}
+				if p.outStringsdicts[table] == nil {
+					p.outStringsdicts[table] = make(map[string]stringsdictEntryMap)
+				}
+				p.outStringsdicts[table][lproj] = em
+			}
+		}
+	}
+}
+
+// validateMissingTranslations reports every key of
+// the development language which is not translated.
+func (p *genstringsContext) validateMissingTranslations() {
+	devLproj := p.devLproj
+	for table, outEntryMap := range p.outEntryMap {
+		devEntryMap, ok := outEntryMap[devLproj]
+		if !ok {
+			continue
+		}
+		keys := []string{}
+		for key := range devEntryMap {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, lproj := range p.lprojs {
+			if lproj == devLproj {
+				continue
+			}
+			targetPath := lproj + "/" + table + dotStringsExt
+			em, ok := outEntryMap[lproj]
+			if !ok {
+				p.diagnostics.Add(positionErr(ruleMissingTranslation, targetPath, 0, 0, "file not found"))
+				continue
+			}
+			for _, key := range keys {
+				if _, ok := em[key]; !ok {
+					p.diagnostics.Add(positionErr(
+						ruleMissingTranslation,
+						targetPath,
+						0,
+						0,
+						fmt.Sprintf("`%v` is not translated", key),
+					))
+				}
+			}
+		}
+	}
+}
+
+// audit checks the localized strings in build products, e.g. an .app.
+// It writes nothing.
+func (p *genstringsContext) audit() error {
+	if err := p.findLprojs(); err != nil {
+		return err
+	}
+	p.readBuildProduct()
+	if err := p.diagnostics.Err(); err != nil {
+		return err
+	}
+	p.validateMissingTranslations()
+	p.validateFormatSpecifiers()
+	p.validatePluralCategories()
+	return p.diagnostics.Err()
+}
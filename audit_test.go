@@ -0,0 +1,109 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/iawaknahc/gogenstrings/bplist"
+	"github.com/iawaknahc/gogenstrings/xmlplist"
+)
+
+func binaryDotStrings(t *testing.T, pairs ...string) string {
+	dict := xmlplist.NewDict()
+	for i := 0; i < len(pairs); i += 2 {
+		dict.Set(pairs[i], 0, 0, xmlplist.Value{Value: pairs[i+1]})
+	}
+	b, err := bplist.PrintBinaryPlist(xmlplist.Value{Value: dict})
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	return string(b)
+}
+
+func TestReadCompiledDotStrings(t *testing.T) {
+	root, err := ioutil.TempDir("", "gogenstrings")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	defer os.RemoveAll(root)
+
+	writeFiles(t, root, map[string]string{
+		"binary.strings": binaryDotStrings(t, "a", "b"),
+		"xml.strings": `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>a</key>
+	<string>b</string>
+</dict>
+</plist>
+`,
+		"text.strings":  `"a" = "b";`,
+		"utf16.strings": string(encode(`"a" = "b";`, encodingUTF16LE)),
+	})
+	for name, line := range map[string]int{"binary": 0, "xml": 5, "text": 1, "utf16": 1} {
+		fullpath := root + "/" + name + ".strings"
+		es, err := readCompiledDotStrings(fullpath)
+		if err != nil {
+			t.Errorf("%v: %v\n", name, err)
+			continue
+		}
+		if len(es) != 1 || es[0].key != "a" || es[0].value != "b" || es[0].startLine != line {
+			t.Errorf("%v: %v\n", name, es)
+		}
+	}
+
+	writeFiles(t, root, map[string]string{
+		"invalid.strings": string(func() []byte {
+			b, _ := bplist.PrintBinaryPlist(xmlplist.Value{Value: []interface{}{}})
+			return b
+		}()),
+	})
+	_, err = readCompiledDotStrings(root + "/invalid.strings")
+	if err == nil || err.Error() != root+"/invalid.strings: not in .strings format" {
+		t.Errorf("%v\n", err)
+	}
+}
+
+func TestAudit(t *testing.T) {
+	root, err := ioutil.TempDir("", "gogenstrings")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	defer os.RemoveAll(root)
+
+	writeFiles(t, root, map[string]string{
+		"My.app/en.lproj/Localizable.strings":     binaryDotStrings(t, "a", "%@ liked your post", "b", "b"),
+		"My.app/ja.lproj/Localizable.strings":     binaryDotStrings(t, "a", "%d"),
+		"My.app/fr.lproj/Localizable.strings":     `"a" = "%@ a aimé"; "b" = "b";`,
+		"My.app/en.lproj/Settings.strings":        binaryDotStrings(t, "c", "c"),
+		"My.app/fr.lproj/Settings.strings":        binaryDotStrings(t, "c", "c"),
+		"My.app/en.lproj/Localizable.stringsdict": devStringsdict,
+		"My.app/ru.lproj/Localizable.stringsdict": devStringsdict,
+	})
+	appPath := root + "/My.app"
+
+	ctx := newGenstringsContext(genstringsOptions{
+		rootPath:    appPath,
+		devlang:     "en",
+		routineName: "NSLocalizedString",
+	})
+	err = ctx.audit()
+	if err == nil {
+		t.Fatalf("expected error\n")
+	}
+	expected := []string{
+		appPath + "/ja.lproj/Localizable.strings: `b` is not translated",
+		appPath + "/ja.lproj/Localizable.strings: `a` uses argument 1 as `%d` but en.lproj uses it as `%@`",
+		appPath + "/ja.lproj/Settings.strings: file not found",
+		appPath + "/ru.lproj/Localizable.strings: file not found",
+		appPath + "/ru.lproj/Localizable.stringsdict:9:3: `n_items` is missing plural category `few` of `items` for ru.lproj",
+		appPath + "/ru.lproj/Localizable.stringsdict:9:3: `n_items` is missing plural category `many` of `items` for ru.lproj",
+		appPath + "/ru.lproj/Settings.strings: file not found",
+	}
+	if actual := err.Error(); actual != strings.Join(expected, "\n") {
+		t.Errorf("%v\n", actual)
+	}
+}
@@ -0,0 +1,444 @@
+package bplist
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+	"unicode/utf16"
+
+	"github.com/iawaknahc/gogenstrings/errors"
+	"github.com/iawaknahc/gogenstrings/xmlplist"
+)
+
+// Magic is the header of binary plist.
+const Magic = "bplist00"
+
+const trailerSize = 32
+
+// Object markers
+const (
+	markerFalse  = 0x08
+	markerTrue   = 0x09
+	markerInt    = 0x10
+	markerReal   = 0x20
+	markerDate   = 0x33
+	markerData   = 0x40
+	markerASCII  = 0x50
+	markerUTF16  = 0x60
+	markerArray  = 0xA0
+	markerDict   = 0xD0
+	countInlined = 0x0F
+)
+
+// referenceDate is the epoch of <date>.
+var referenceDate = time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// dateFromSeconds returns the date seconds after referenceDate.
+// time.Duration overflows beyond about 292 years
+// so whole and fractional seconds are added separately.
+func dateFromSeconds(seconds float64) time.Time {
+	whole, frac := math.Modf(seconds)
+	return time.Unix(referenceDate.Unix()+int64(whole), int64(frac*1e9)).UTC()
+}
+
+// secondsFromDate is the inverse of dateFromSeconds.
+func secondsFromDate(t time.Time) float64 {
+	return float64(t.Unix()-referenceDate.Unix()) + float64(t.Nanosecond())/1e9
+}
+
+type trailer struct {
+	offsetIntSize     int
+	objectRefSize     int
+	numObjects        uint64
+	topObject         uint64
+	offsetTableOffset uint64
+}
+
+type binaryPlistParser struct {
+	src      []byte
+	filepath string
+	trailer  trailer
+	offsets  []uint64
+	// visiting detects reference cycles.
+	visiting map[uint64]bool
+	// objects are the decoded objects by ref.
+	// An object can be referenced many times
+	// so it is decoded once.
+	objects map[uint64]xmlplist.Value
+}
+
+func (p *binaryPlistParser) recover(errp *error) {
+	if r := recover(); r != nil {
+		if err, ok := r.(errors.ErrFile); ok {
+			*errp = err
+		} else {
+			panic(r)
+		}
+	}
+}
+
+func (p *binaryPlistParser) fail(format string, args ...interface{}) {
+	panic(errors.File(p.filepath, fmt.Sprintf(format, args...)))
+}
+
+// bytes returns src[offset:offset+n].
+func (p *binaryPlistParser) bytes(offset, n uint64) []byte {
+	end := offset + n
+	if end < offset || end > uint64(len(p.src)) {
+		p.fail("unexpected end of binary plist at offset %v", offset)
+	}
+	return p.src[offset:end]
+}
+
+// uint reads a big-endian unsigned integer of size bytes.
+func (p *binaryPlistParser) uint(offset uint64, size int) uint64 {
+	var out uint64
+	for _, b := range p.bytes(offset, uint64(size)) {
+		out = out<<8 | uint64(b)
+	}
+	return out
+}
+
+func (p *binaryPlistParser) parseTrailer() {
+	if len(p.src) < len(Magic)+trailerSize || string(p.src[:len(Magic)]) != Magic {
+		p.fail("not a binary plist")
+	}
+	offset := uint64(len(p.src) - trailerSize)
+	t := trailer{
+		offsetIntSize:     int(p.src[offset+6]),
+		objectRefSize:     int(p.src[offset+7]),
+		numObjects:        p.uint(offset+8, 8),
+		topObject:         p.uint(offset+16, 8),
+		offsetTableOffset: p.uint(offset+24, 8),
+	}
+	if t.offsetIntSize < 1 || t.offsetIntSize > 8 || t.objectRefSize < 1 || t.objectRefSize > 8 {
+		p.fail("invalid trailer")
+	}
+	if t.topObject >= t.numObjects {
+		p.fail("invalid top object %v", t.topObject)
+	}
+	if t.numObjects > uint64(len(p.src)) {
+		p.fail("invalid number of objects %v", t.numObjects)
+	}
+	p.trailer = t
+
+	p.offsets = make([]uint64, t.numObjects)
+	for i := range p.offsets {
+		objectOffset := p.uint(t.offsetTableOffset+uint64(i*t.offsetIntSize), t.offsetIntSize)
+		if objectOffset < uint64(len(Magic)) || objectOffset >= t.offsetTableOffset {
+			p.fail("invalid offset %v of object %v", objectOffset, i)
+		}
+		p.offsets[i] = objectOffset
+	}
+}
+
+// count returns the count of the object at offset
+// and the offset of its content.
+func (p *binaryPlistParser) count(offset uint64) (uint64, uint64) {
+	marker := p.bytes(offset, 1)[0]
+	n := uint64(marker & 0x0F)
+	if n != countInlined {
+		return n, offset + 1
+	}
+	intMarker := p.bytes(offset+1, 1)[0]
+	if intMarker&0xF0 != markerInt {
+		p.fail("invalid count at offset %v", offset)
+	}
+	size := 1 << (intMarker & 0x0F)
+	if size > 8 {
+		p.fail("invalid count at offset %v", offset)
+	}
+	n = p.uint(offset+2, size)
+	// Nothing can be larger than the file.
+	if n > uint64(len(p.src)) {
+		p.fail("invalid count at offset %v", offset)
+	}
+	return n, offset + 2 + uint64(size)
+}
+
+func (p *binaryPlistParser) ref(offset uint64, i uint64) uint64 {
+	size := uint64(p.trailer.objectRefSize)
+	return p.uint(offset+i*size, int(size))
+}
+
+func (p *binaryPlistParser) parseObject(ref uint64) xmlplist.Value {
+	if ref >= uint64(len(p.offsets)) {
+		p.fail("invalid object reference %v", ref)
+	}
+	if value, ok := p.objects[ref]; ok {
+		return value
+	}
+	if p.visiting[ref] {
+		p.fail("cyclic object reference %v", ref)
+	}
+	p.visiting[ref] = true
+	value := p.decodeObject(ref)
+	delete(p.visiting, ref)
+	p.objects[ref] = value
+	return value
+}
+
+func (p *binaryPlistParser) decodeObject(ref uint64) xmlplist.Value {
+	offset := p.offsets[ref]
+	marker := p.bytes(offset, 1)[0]
+	switch marker & 0xF0 {
+	case 0x00:
+		switch marker {
+		case markerFalse:
+			return xmlplist.Value{Value: false}
+		case markerTrue:
+			return xmlplist.Value{Value: true}
+		}
+	case markerInt:
+		size := 1 << (marker & 0x0F)
+		switch size {
+		case 1, 2, 4, 8:
+			// Only 8-byte integer is signed.
+			return xmlplist.Value{Value: int64(p.uint(offset+1, size))}
+		case 16:
+			// The upper 8 bytes are the sign extension.
+			return xmlplist.Value{Value: int64(p.uint(offset+9, 8))}
+		}
+	case markerReal:
+		switch size := 1 << (marker & 0x0F); size {
+		case 4:
+			return xmlplist.Value{Value: float64(math.Float32frombits(uint32(p.uint(offset+1, 4))))}
+		case 8:
+			return xmlplist.Value{Value: math.Float64frombits(p.uint(offset+1, 8))}
+		}
+	case markerDate & 0xF0:
+		if marker == markerDate {
+			seconds := math.Float64frombits(p.uint(offset+1, 8))
+			return xmlplist.Value{Value: dateFromSeconds(seconds)}
+		}
+	case markerData:
+		n, start := p.count(offset)
+		data := make([]byte, n)
+		copy(data, p.bytes(start, n))
+		return xmlplist.Value{Value: data}
+	case markerASCII:
+		n, start := p.count(offset)
+		return xmlplist.Value{Value: string(p.bytes(start, n))}
+	case markerUTF16:
+		n, start := p.count(offset)
+		b := p.bytes(start, 2*n)
+		units := make([]uint16, n)
+		for i := range units {
+			units[i] = binary.BigEndian.Uint16(b[2*i:])
+		}
+		return xmlplist.Value{Value: string(utf16.Decode(units))}
+	case markerArray:
+		n, start := p.count(offset)
+		// Check the references before allocating.
+		p.bytes(start, n*uint64(p.trailer.objectRefSize))
+		out := make([]interface{}, n)
+		for i := range out {
+			out[i] = p.parseObject(p.ref(start, uint64(i)))
+		}
+		return xmlplist.Value{Value: out}
+	case markerDict:
+		n, start := p.count(offset)
+		p.bytes(start, 2*n*uint64(p.trailer.objectRefSize))
+		out := xmlplist.NewDict()
+		for i := uint64(0); i < n; i++ {
+			key := p.parseObject(p.ref(start, i))
+			k, ok := key.Value.(string)
+			if !ok {
+				p.fail("dict key of object %v is not a string", ref)
+			}
+			if _, ok := out.Get(k); ok {
+				p.fail("duplicated key `%v`", k)
+			}
+			value := p.parseObject(p.ref(start, n+i))
+			out.Set(k, 0, 0, value)
+		}
+		return xmlplist.Value{Value: out}
+	}
+	p.fail("unsupported object 0x%02x at offset %v", marker, offset)
+	return xmlplist.Value{}
+}
+
+// ParseBinaryPlist parses bplist00.
+// The values have no line and col.
+func ParseBinaryPlist(src []byte, filepath string) (out xmlplist.Value, err error) {
+	p := binaryPlistParser{
+		src:      src,
+		filepath: filepath,
+		visiting: make(map[uint64]bool),
+		objects:  make(map[uint64]xmlplist.Value),
+	}
+	defer p.recover(&err)
+	p.parseTrailer()
+	out = p.parseObject(p.trailer.topObject)
+	return out, err
+}
+
+type binaryPlistPrinter struct {
+	// objects are the encoded objects without references.
+	objects []object
+}
+
+// object is an encoded object.
+// refs are filled in once the ref size is known.
+type object struct {
+	header []byte
+	refs   []int
+}
+
+func appendUint(b []byte, v uint64, size int) []byte {
+	for i := size - 1; i >= 0; i-- {
+		b = append(b, byte(v>>(8*uint(i))))
+	}
+	return b
+}
+
+func intSize(v uint64) int {
+	switch {
+	case v <= math.MaxUint8:
+		return 1
+	case v <= math.MaxUint16:
+		return 2
+	case v <= math.MaxUint32:
+		return 4
+	}
+	return 8
+}
+
+func appendInt(b []byte, v int64) []byte {
+	if v < 0 {
+		return appendUint(append(b, markerInt|3), uint64(v), 8)
+	}
+	size := intSize(uint64(v))
+	var exp byte
+	for 1<<exp < size {
+		exp++
+	}
+	return appendUint(append(b, markerInt|exp), uint64(v), size)
+}
+
+func appendMarker(b []byte, marker byte, n int) []byte {
+	if n < countInlined {
+		return append(b, marker|byte(n))
+	}
+	return appendInt(append(b, marker|countInlined), int64(n))
+}
+
+func (p *binaryPlistPrinter) add(o object) int {
+	p.objects = append(p.objects, o)
+	return len(p.objects) - 1
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *binaryPlistPrinter) printValue(v interface{}) (int, error) {
+	value, ok := v.(xmlplist.Value)
+	if !ok {
+		return 0, fmt.Errorf("expected Value but got %T", v)
+	}
+	var b []byte
+	switch x := value.Value.(type) {
+	case string:
+		if isASCII(x) {
+			b = append(appendMarker(b, markerASCII, len(x)), x...)
+		} else {
+			units := utf16.Encode([]rune(x))
+			b = appendMarker(b, markerUTF16, len(units))
+			for _, u := range units {
+				b = appendUint(b, uint64(u), 2)
+			}
+		}
+	case float64:
+		b = appendUint(append(b, markerReal|3), math.Float64bits(x), 8)
+	case int64:
+		b = appendInt(b, x)
+	case bool:
+		if x {
+			b = append(b, markerTrue)
+		} else {
+			b = append(b, markerFalse)
+		}
+	case time.Time:
+		b = appendUint(append(b, markerDate), math.Float64bits(secondsFromDate(x)), 8)
+	case []byte:
+		b = append(appendMarker(b, markerData, len(x)), x...)
+	case []interface{}:
+		index := p.add(object{header: appendMarker(nil, markerArray, len(x))})
+		refs := make([]int, len(x))
+		for i, item := range x {
+			ref, err := p.printValue(item)
+			if err != nil {
+				return 0, err
+			}
+			refs[i] = ref
+		}
+		p.objects[index].refs = refs
+		return index, nil
+	case xmlplist.Dict:
+		index := p.add(object{header: appendMarker(nil, markerDict, x.Len())})
+		refs := make([]int, 2*x.Len())
+		for i, keyValue := range x.Keys() {
+			keyRef, err := p.printValue(xmlplist.Value{Value: keyValue.Value})
+			if err != nil {
+				return 0, err
+			}
+			value, _ := x.Get(keyValue.Value.(string))
+			valueRef, err := p.printValue(value)
+			if err != nil {
+				return 0, err
+			}
+			refs[i] = keyRef
+			refs[x.Len()+i] = valueRef
+		}
+		p.objects[index].refs = refs
+		return index, nil
+	default:
+		return 0, fmt.Errorf("unsupported type %T", value.Value)
+	}
+	return p.add(object{header: b}), nil
+}
+
+// PrintBinaryPlist prints value in bplist00.
+// The elements of <array> must be Value.
+func PrintBinaryPlist(value xmlplist.Value) ([]byte, error) {
+	p := binaryPlistPrinter{}
+	top, err := p.printValue(value)
+	if err != nil {
+		return nil, err
+	}
+
+	refSize := intSize(uint64(len(p.objects)))
+	buf := bytes.Buffer{}
+	buf.WriteString(Magic)
+	offsets := make([]uint64, len(p.objects))
+	for i, o := range p.objects {
+		offsets[i] = uint64(buf.Len())
+		b := o.header
+		for _, ref := range o.refs {
+			b = appendUint(b, uint64(ref), refSize)
+		}
+		buf.Write(b)
+	}
+
+	offsetTableOffset := uint64(buf.Len())
+	offsetIntSize := intSize(offsetTableOffset)
+	for _, offset := range offsets {
+		buf.Write(appendUint(nil, offset, offsetIntSize))
+	}
+
+	t := make([]byte, 6, trailerSize)
+	t = append(t, byte(offsetIntSize), byte(refSize))
+	t = appendUint(t, uint64(len(p.objects)), 8)
+	t = appendUint(t, uint64(top), 8)
+	t = appendUint(t, offsetTableOffset, 8)
+	buf.Write(t)
+	return buf.Bytes(), nil
+}
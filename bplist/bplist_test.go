@@ -0,0 +1,143 @@
+package bplist
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iawaknahc/gogenstrings/xmlplist"
+)
+
+func trailerBytes(offsetIntSize, refSize byte, numObjects, topObject, offsetTableOffset byte) []byte {
+	t := make([]byte, 32)
+	t[6] = offsetIntSize
+	t[7] = refSize
+	t[15] = numObjects
+	t[23] = topObject
+	t[31] = offsetTableOffset
+	return t
+}
+
+func TestParseBinaryPlist(t *testing.T) {
+	// {"a": "b"} as written by plutil
+	input := []byte("bplist00\xD1\x01\x02\x51a\x51b\x08\x0B\x0D")
+	input = append(input, trailerBytes(1, 1, 3, 0, 15)...)
+
+	value, err := ParseBinaryPlist(input, "a")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if !reflect.DeepEqual(value.Flatten(), map[string]interface{}{"a": "b"}) {
+		t.Errorf("%v\n", value.Flatten())
+	}
+
+	actual, err := PrintBinaryPlist(value)
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if !bytes.Equal(actual, input) {
+		t.Errorf("%q\n", actual)
+	}
+}
+
+func TestBinaryPlistRoundTrip(t *testing.T) {
+	dict := xmlplist.NewDict()
+	set := func(key string, value interface{}) {
+		dict.Set(key, 0, 0, xmlplist.Value{Value: value})
+	}
+	set("string", "hello")
+	set("unicode", "こんにちは😀")
+	set("long", strings.Repeat("a", 300))
+	set("int8", int64(255))
+	set("int16", int64(65535))
+	set("int32", int64(1<<32-1))
+	set("int64", int64(1<<40))
+	set("negative", int64(-1))
+	set("real", 1.5)
+	set("true", true)
+	set("false", false)
+	set("date", time.Date(2017, 12, 25, 0, 0, 0, 0, time.UTC))
+	set("fraction", time.Date(2017, 12, 25, 0, 0, 0, 500000000, time.UTC))
+	// NSDate.distantFuture and NSDate.distantPast
+	set("distantFuture", time.Date(4001, 1, 1, 0, 0, 0, 0, time.UTC))
+	set("distantPast", time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC))
+	set("data", []byte{0, 1, 2})
+	set("array", []interface{}{xmlplist.Value{Value: "a"}, xmlplist.Value{Value: int64(1)}})
+	set("dict", xmlplist.NewDict())
+	input := xmlplist.Value{Value: dict}
+
+	b, err := PrintBinaryPlist(input)
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	actual, err := ParseBinaryPlist(b, "a")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if !reflect.DeepEqual(actual.Flatten(), input.Flatten()) {
+		t.Errorf("%v\n", actual.Flatten())
+	}
+	// Key order is preserved
+	actualDict := actual.Value.(xmlplist.Dict)
+	for i, key := range actualDict.Keys() {
+		if key.Value != dict.Keys()[i].Value {
+			t.Errorf("%v: %v\n", i, key.Value)
+		}
+	}
+}
+
+func TestParseBinaryPlistInvalid(t *testing.T) {
+	cyclic := append([]byte("bplist00\xA1\x00\x08"), trailerBytes(1, 1, 1, 0, 10)...)
+	truncated := append([]byte("bplist00\x5Fa\x08"), trailerBytes(1, 1, 1, 0, 10)...)
+	badRef := append([]byte("bplist00\xA1\x05\x08"), trailerBytes(1, 1, 1, 0, 10)...)
+	cases := []struct {
+		input    []byte
+		expected string
+	}{
+		{[]byte("bplist00"), "a: not a binary plist"},
+		{append([]byte("<?xml"), make([]byte, 40)...), "a: not a binary plist"},
+		{cyclic, "a: cyclic object reference 0"},
+		{truncated, "a: invalid count at offset 8"},
+		{badRef, "a: invalid object reference 5"},
+	}
+	for _, c := range cases {
+		_, err := ParseBinaryPlist(c.input, "a")
+		if err == nil || err.Error() != c.expected {
+			t.Errorf("%q: %v\n", c.input, err)
+		}
+	}
+}
+
+func TestParseBinaryPlistSharedObjects(t *testing.T) {
+	// Object i is an array referencing object i+1 twice.
+	// It takes 2^n steps without decoding every object once.
+	n := 40
+	src := []byte(Magic)
+	offsets := []byte{}
+	for i := 0; i < n; i++ {
+		offsets = append(offsets, byte(len(src)))
+		src = append(src, 0xA2, byte(i+1), byte(i+1))
+	}
+	offsets = append(offsets, byte(len(src)))
+	src = append(src, markerTrue)
+	offsetTableOffset := byte(len(src))
+	src = append(src, offsets...)
+	src = append(src, trailerBytes(1, 1, byte(n+1), 0, offsetTableOffset)...)
+
+	value, err := ParseBinaryPlist(src, "a")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	for i := 0; i < n; i++ {
+		array := value.Value.([]interface{})
+		if len(array) != 2 {
+			t.Fatalf("%v: %v\n", i, array)
+		}
+		value = array[1].(xmlplist.Value)
+	}
+	if value.Value != true {
+		t.Errorf("%v\n", value)
+	}
+}
@@ -0,0 +1,506 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/iawaknahc/gogenstrings/errors"
+	"github.com/iawaknahc/gogenstrings/xmlplist"
+)
+
+const ruleUnrepresentable = "unrepresentable"
+
+const (
+	convertToStringCatalog = "xcstrings"
+	convertToDotStrings    = "strings"
+)
+
+// stringsdictVariableRe matches a variable in NSStringLocalizedFormatKey,
+// e.g. "%#@items@" or "%1$#@items@".
+var stringsdictVariableRe = regexp.MustCompile(`%(?:[0-9]+\$)?#@([^@]+)@`)
+
+// substitutionArg is the argument of a substitution.
+const substitutionArg = "%arg"
+
+// stringCatalogVariable is the variable of a plural
+// converted from a String Catalog.
+const stringCatalogVariable = "count"
+
+// unrepresentableErr is positionErr with ruleUnrepresentable.
+func unrepresentableErr(filepath string, line, col int, msg string) error {
+	return positionErr(ruleUnrepresentable, filepath, line, col, msg)
+}
+
+// pluralFromStringsdict converts e to a localization.
+// A single variable becomes variations.
+// Otherwise every variable becomes a substitution.
+func pluralFromStringsdict(e stringsdictEntry) (stringCatalogLocalization, error) {
+	out := stringCatalogLocalization{}
+	formatKeyValue, ok := e.value.Get(stringsdictFormatKey)
+	formatKey, isString := formatKeyValue.Value.(string)
+	if !ok || !isString {
+		return out, unrepresentableErr(
+			e.filepath,
+			e.startLine,
+			e.startCol,
+			fmt.Sprintf("`%v` has no %v", e.key, stringsdictFormatKey),
+		)
+	}
+
+	rules := map[string]bool{}
+	for _, keyValue := range e.pluralRules() {
+		rules[keyValue.Value.(string)] = true
+	}
+	variables := []string{}
+	seen := map[string]bool{}
+	for _, match := range stringsdictVariableRe.FindAllStringSubmatch(formatKey, -1) {
+		variable := match[1]
+		if !rules[variable] {
+			return out, unrepresentableErr(
+				e.filepath,
+				e.startLine,
+				e.startCol,
+				fmt.Sprintf("`%v` uses `%v` which is not a plural rule", e.key, variable),
+			)
+		}
+		if !seen[variable] {
+			seen[variable] = true
+			variables = append(variables, variable)
+		}
+	}
+
+	categoriesOf := func(variable string) (string, map[string]string) {
+		rule, _ := e.value.Get(variable)
+		dict := rule.Value.(xmlplist.Dict)
+		valueType := ""
+		if v, ok := dict.Get(stringsdictValueTypeKey); ok {
+			valueType, _ = v.Value.(string)
+		}
+		categories := map[string]string{}
+		for _, k := range dict.Keys() {
+			category := k.Value.(string)
+			if !isPluralCategory(category) {
+				continue
+			}
+			v, _ := dict.Get(category)
+			categories[category], _ = v.Value.(string)
+		}
+		return valueType, categories
+	}
+
+	if len(variables) == 1 && formatKey == "%#@"+variables[0]+"@" {
+		_, categories := categoriesOf(variables[0])
+		out.Variations = stringCatalogVariations{
+			"plural": pluralVariation(categories),
+		}
+		return out, nil
+	}
+
+	out.StringUnit = &stringCatalogStringUnit{
+		State: stringUnitStateTranslated,
+		Value: formatKey,
+	}
+	out.Substitutions = make(map[string]stringCatalogSubstitution)
+	for i, variable := range variables {
+		valueType, categories := categoriesOf(variable)
+		// The argument can be positional, e.g. "%1$d".
+		argRe := regexp.MustCompile(`%(?:[0-9]+\$)?` + regexp.QuoteMeta(valueType))
+		for category, value := range categories {
+			categories[category] = argRe.ReplaceAllLiteralString(value, substitutionArg)
+		}
+		out.Substitutions[variable] = stringCatalogSubstitution{
+			ArgNum:          i + 1,
+			FormatSpecifier: valueType,
+			Variations: stringCatalogVariations{
+				"plural": pluralVariation(categories),
+			},
+		}
+	}
+	return out, nil
+}
+
+func pluralVariation(categories map[string]string) map[string]stringCatalogLocalization {
+	out := map[string]stringCatalogLocalization{}
+	for category, value := range categories {
+		out[category] = stringCatalogLocalization{
+			StringUnit: &stringCatalogStringUnit{
+				State: stringUnitStateTranslated,
+				Value: value,
+			},
+		}
+	}
+	return out
+}
+
+// stringsdictFromPlural is the inverse of pluralFromStringsdict.
+func stringsdictFromPlural(key string, l stringCatalogLocalization) stringsdictEntry {
+	value := xmlplist.NewDict()
+	setString := func(dict *xmlplist.Dict, key, value string) {
+		dict.Set(key, 0, 0, xmlplist.Value{Value: value})
+	}
+	ruleDict := func(valueType string, variation map[string]stringCatalogLocalization, replace bool) xmlplist.Dict {
+		dict := xmlplist.NewDict()
+		setString(&dict, stringsdictSpecTypeKey, stringsdictPluralRuleType)
+		setString(&dict, stringsdictValueTypeKey, valueType)
+		for _, category := range pluralCategoryOrder {
+			v, ok := variation[category]
+			if !ok || v.StringUnit == nil {
+				continue
+			}
+			s := v.StringUnit.Value
+			if replace {
+				s = strings.Replace(s, substitutionArg, "%"+valueType, -1)
+			}
+			setString(&dict, category, s)
+		}
+		return dict
+	}
+
+	if l.StringUnit == nil {
+		variation := l.Variations["plural"]
+		valueType := "d"
+		if other, ok := variation["other"]; ok && other.StringUnit != nil {
+			if specs, err := parseFormatSpecifiers(other.StringUnit.Value); err == nil && len(specs) > 0 {
+				valueType = specs[0].length + string(specs[0].conversion)
+			}
+		}
+		setString(&value, stringsdictFormatKey, "%#@"+stringCatalogVariable+"@")
+		value.Set(
+			stringCatalogVariable, 0, 0,
+			xmlplist.Value{Value: ruleDict(valueType, variation, false)},
+		)
+	} else {
+		setString(&value, stringsdictFormatKey, l.StringUnit.Value)
+		names := []string{}
+		for name := range l.Substitutions {
+			names = append(names, name)
+		}
+		sort.Slice(names, func(i, j int) bool {
+			return l.Substitutions[names[i]].ArgNum < l.Substitutions[names[j]].ArgNum
+		})
+		for _, name := range names {
+			s := l.Substitutions[name]
+			value.Set(
+				name, 0, 0,
+				xmlplist.Value{Value: ruleDict(s.FormatSpecifier, s.Variations["plural"], true)},
+			)
+		}
+	}
+	return stringsdictEntry{
+		key:   key,
+		value: value,
+	}
+}
+
+// checkRepresentable reports what .strings and .stringsdict
+// cannot represent in l.
+func checkRepresentable(catalogPath, key, language string, l stringCatalogLocalization) error {
+	for kind := range l.Variations {
+		if kind != "plural" || l.StringUnit != nil {
+			return unrepresentableErr(
+				catalogPath,
+				0,
+				0,
+				fmt.Sprintf("`%v` varies by %v in %v", key, kind, language),
+			)
+		}
+	}
+	for name, s := range l.Substitutions {
+		for kind := range s.Variations {
+			if kind != "plural" {
+				return unrepresentableErr(
+					catalogPath,
+					0,
+					0,
+					fmt.Sprintf("`%v` varies `%v` by %v in %v", key, name, kind, language),
+				)
+			}
+		}
+	}
+	return nil
+}
+
+// toStringCatalogs converts the tables read by readBuildProduct.
+func (p *genstringsContext) toStringCatalogs() map[string]stringCatalog {
+	devLproj := p.devLproj
+	sourceLanguage := languageOfLproj(devLproj)
+	out := make(map[string]stringCatalog)
+
+	tables := map[string]bool{}
+	for table := range p.outEntryMap {
+		tables[table] = true
+	}
+	for table := range p.outStringsdicts {
+		tables[table] = true
+	}
+
+	for table := range tables {
+		c := newStringCatalog(sourceLanguage)
+		devEntryMap := p.outEntryMap[table][devLproj]
+		for _, lproj := range p.lprojs {
+			language := languageOfLproj(lproj)
+			for key, e := range p.outEntryMap[table][lproj] {
+				ce := c.Strings[key]
+				comment := strings.TrimSpace(e.comment)
+				if comment == noCommentProvided {
+					comment = ""
+				}
+				if lproj == devLproj {
+					ce.Comment = comment
+				} else if _, ok := devEntryMap[key]; !ok {
+					// Translation of a key not in the development language
+					ce.ExtractionState = extractionStateStale
+				} else if devComment := strings.TrimSpace(devEntryMap[key].comment); comment != "" && devComment != noCommentProvided && comment != devComment {
+					p.diagnostics.Add(unrepresentableErr(
+						e.filepath,
+						e.startLine,
+						e.startCol,
+						fmt.Sprintf("comment of `%v` differs from %v", key, filepath.Base(devLproj)),
+					))
+				}
+				// The key is the value of the source language by default.
+				if lproj != devLproj || e.value != key {
+					if ce.Localizations == nil {
+						ce.Localizations = make(map[string]stringCatalogLocalization)
+					}
+					ce.Localizations[language] = stringCatalogLocalization{
+						StringUnit: &stringCatalogStringUnit{
+							State: stringUnitStateTranslated,
+							Value: e.value,
+						},
+					}
+				}
+				c.Strings[key] = ce
+			}
+			// .stringsdict takes precedence over .strings as in Foundation.
+			for key, e := range p.outStringsdicts[table][lproj] {
+				if shadowed, ok := p.outEntryMap[table][lproj][key]; ok {
+					p.diagnostics.Add(unrepresentableErr(
+						shadowed.filepath,
+						shadowed.startLine,
+						shadowed.startCol,
+						fmt.Sprintf("`%v` is also in %v", key, filepath.Base(e.filepath)),
+					))
+				}
+				l, err := pluralFromStringsdict(e)
+				if err != nil {
+					p.diagnostics.Add(err)
+					continue
+				}
+				ce := c.Strings[key]
+				if ce.Localizations == nil {
+					ce.Localizations = make(map[string]stringCatalogLocalization)
+				}
+				ce.Localizations[language] = l
+				c.Strings[key] = ce
+			}
+		}
+		out[filepath.Join(filepath.Dir(devLproj), table+dotXCStringsExt)] = c
+	}
+	return out
+}
+
+// toDotStrings converts the String Catalogs read by readStringCatalogs.
+// The key is the target path.
+func (p *genstringsContext) toDotStrings() (map[string]entryMap, map[string]stringsdictEntryMap) {
+	dotStrings := make(map[string]entryMap)
+	stringsdicts := make(map[string]stringsdictEntryMap)
+	for table, c := range p.inStringCatalogs {
+		catalogPath := p.stringCatalogPaths[table]
+		dir := filepath.Dir(catalogPath)
+		targetPath := func(language, ext string) string {
+			return filepath.Join(dir, language+".lproj", table+ext)
+		}
+		for key, ce := range c.Strings {
+			comment := ce.Comment
+			if comment == "" {
+				comment = noCommentProvided
+			}
+			if ce.ShouldTranslate != nil && !*ce.ShouldTranslate {
+				p.diagnostics.Add(unrepresentableErr(
+					catalogPath,
+					0,
+					0,
+					fmt.Sprintf("`%v` should not be translated", key),
+				))
+			}
+			// The source language falls back to the key.
+			if _, ok := ce.Localizations[c.SourceLanguage]; !ok {
+				path := targetPath(c.SourceLanguage, dotStringsExt)
+				if dotStrings[path] == nil {
+					dotStrings[path] = entryMap{}
+				}
+				dotStrings[path][key] = entry{
+					comment: comment,
+					key:     key,
+					value:   key,
+				}
+			}
+			for language, l := range ce.Localizations {
+				if err := checkRepresentable(catalogPath, key, language, l); err != nil {
+					p.diagnostics.Add(err)
+					continue
+				}
+				if len(l.Variations) > 0 || len(l.Substitutions) > 0 {
+					path := targetPath(language, dotStringsdictExt)
+					if stringsdicts[path] == nil {
+						stringsdicts[path] = stringsdictEntryMap{}
+					}
+					stringsdicts[path][key] = stringsdictFromPlural(key, l)
+					continue
+				}
+				if l.StringUnit == nil {
+					continue
+				}
+				path := targetPath(language, dotStringsExt)
+				if dotStrings[path] == nil {
+					dotStrings[path] = entryMap{}
+				}
+				dotStrings[path][key] = entry{
+					comment: comment,
+					key:     key,
+					value:   l.StringUnit.Value,
+				}
+			}
+		}
+	}
+	return dotStrings, stringsdicts
+}
+
+// findConvertedLprojs finds the lprojs to convert
+// apart from those excluded.
+// They must be in the directory of the development lproj
+// because lprojs of the same language in other directories
+// would be merged into the same String Catalog.
+func (p *genstringsContext) findConvertedLprojs() error {
+	if err := p.resolveDevlang(); err != nil {
+		return err
+	}
+	if err := p.findAllLprojs(); err != nil {
+		return err
+	}
+	if p.excludeRegexp != nil {
+		lprojs := []string{}
+		for _, lproj := range p.lprojs {
+			if !p.excludeRegexp.MatchString(lproj) {
+				lprojs = append(lprojs, lproj)
+			}
+		}
+		p.lprojs = lprojs
+	}
+	if !p.findDevLproj() {
+		return p.devLprojNotFoundErr()
+	}
+	dir := filepath.Dir(p.devLproj)
+	for _, lproj := range p.lprojs {
+		if filepath.Dir(lproj) != dir {
+			p.diagnostics.Add(errors.File(
+				lproj,
+				fmt.Sprintf("lproj is not in %v; use -exclude to convert one directory at a time", dir),
+			))
+		}
+	}
+	return p.diagnostics.Err()
+}
+
+// convert converts .strings and .stringsdict in lprojs to
+// String Catalogs, or String Catalogs back to
+// .strings and .stringsdict.
+// Nothing is written if anything cannot be represented.
+// The converted files are removed only if remove is true
+// and every file has been written.
+func (p *genstringsContext) convert(to string, remove bool) error {
+	out := make(map[string]string)
+	removed := []string{}
+	switch to {
+	case convertToStringCatalog:
+		if err := p.findConvertedLprojs(); err != nil {
+			return err
+		}
+		// Like audit, every file in lprojs is read
+		// without merging routine calls.
+		p.readBuildProduct()
+		if err := p.diagnostics.Err(); err != nil {
+			return err
+		}
+		for targetPath, c := range p.toStringCatalogs() {
+			if _, err := os.Stat(targetPath); err == nil {
+				p.diagnostics.Add(errors.File(targetPath, "file already exists"))
+			}
+			out[targetPath] = c.print()
+		}
+		for table, outEntryMap := range p.outEntryMap {
+			for lproj := range outEntryMap {
+				removed = append(removed, lproj+"/"+table+dotStringsExt)
+			}
+		}
+		for table, outStringsdict := range p.outStringsdicts {
+			for lproj := range outStringsdict {
+				removed = append(removed, lproj+"/"+table+dotStringsdictExt)
+			}
+		}
+	case convertToDotStrings:
+		if err := p.findStringCatalogs(); err != nil {
+			return err
+		}
+		tables := []string{}
+		for table := range p.stringCatalogPaths {
+			tables = append(tables, table)
+		}
+		sort.Strings(tables)
+		p.readStringCatalogs(tables)
+		if err := p.diagnostics.Err(); err != nil {
+			return err
+		}
+		dotStrings, stringsdicts := p.toDotStrings()
+		for targetPath, em := range dotStrings {
+			out[targetPath] = em.toEntries().sort().print(false)
+		}
+		for targetPath, em := range stringsdicts {
+			out[targetPath] = em.print()
+		}
+		for targetPath := range out {
+			if _, err := os.Stat(targetPath); err == nil {
+				p.diagnostics.Add(errors.File(targetPath, "file already exists"))
+			}
+		}
+		for _, catalogPath := range p.stringCatalogPaths {
+			removed = append(removed, catalogPath)
+		}
+	default:
+		return fmt.Errorf("unknown conversion `%v`", to)
+	}
+
+	if err := p.diagnostics.Err(); err != nil {
+		return err
+	}
+	// Files already written are removed on failure
+	// so that the conversion can be retried.
+	written := []string{}
+	for targetPath, content := range out {
+		err := os.MkdirAll(filepath.Dir(targetPath), 0755)
+		if err == nil {
+			err = writeFile(targetPath, content, p.targetEncoding(targetPath))
+		}
+		if err != nil {
+			for _, fullpath := range written {
+				os.Remove(fullpath)
+			}
+			return err
+		}
+		written = append(written, targetPath)
+	}
+	if !remove {
+		return nil
+	}
+	for _, fullpath := range removed {
+		if err := os.Remove(fullpath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
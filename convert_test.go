@@ -0,0 +1,300 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	root, err := ioutil.TempDir("", "gogenstrings")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	defer os.RemoveAll(root)
+
+	writeFiles(t, root, map[string]string{
+		"en.lproj/Localizable.strings":     "/* A */\n\"a\" = \"a\";\n\n/* No comment provided by engineer. */\n\"b\" = \"B\";\n\n",
+		"en.lproj/Localizable.stringsdict": devStringsdict,
+		"ja.lproj/Localizable.strings":     "/* A */\n\"a\" = \"エー\";\n",
+	})
+	catalogPath := filepath.Join(root, "Localizable.xcstrings")
+
+	ctx := newGenstringsContext(genstringsOptions{
+		rootPath:    root,
+		devlang:     "en",
+		routineName: "NSLocalizedString",
+	})
+	if err := ctx.convert(convertToStringCatalog, true); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	actual, err := readFile(catalogPath)
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	expected := `{
+  "sourceLanguage" : "en",
+  "strings" : {
+    "a" : {
+      "comment" : "A",
+      "localizations" : {
+        "ja" : {
+          "stringUnit" : {
+            "state" : "translated",
+            "value" : "エー"
+          }
+        }
+      }
+    },
+    "b" : {
+      "localizations" : {
+        "en" : {
+          "stringUnit" : {
+            "state" : "translated",
+            "value" : "B"
+          }
+        }
+      }
+    },
+    "n_items" : {
+      "localizations" : {
+        "en" : {
+          "variations" : {
+            "plural" : {
+              "one" : {
+                "stringUnit" : {
+                  "state" : "translated",
+                  "value" : "%d item"
+                }
+              },
+              "other" : {
+                "stringUnit" : {
+                  "state" : "translated",
+                  "value" : "%d items"
+                }
+              }
+            }
+          }
+        }
+      }
+    }
+  },
+  "version" : "1.0"
+}
+`
+	if actual != expected {
+		t.Errorf("%v\n", actual)
+	}
+	for _, name := range []string{"en.lproj/Localizable.strings", "en.lproj/Localizable.stringsdict", "ja.lproj/Localizable.strings"} {
+		if _, err := os.Stat(filepath.Join(root, name)); !os.IsNotExist(err) {
+			t.Errorf("%v: %v\n", name, err)
+		}
+	}
+
+	ctx = newGenstringsContext(genstringsOptions{
+		rootPath:    root,
+		devlang:     "en",
+		routineName: "NSLocalizedString",
+	})
+	if err := ctx.convert(convertToDotStrings, true); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	expectedFiles := map[string]string{
+		"en.lproj/Localizable.strings": "/* A */\n\"a\" = \"a\";\n\n/* No comment provided by engineer. */\n\"b\" = \"B\";\n\n",
+		"ja.lproj/Localizable.strings": "/* A */\n\"a\" = \"エー\";\n\n",
+		"en.lproj/Localizable.stringsdict": stringsdictHeader + `<dict>
+	<key>n_items</key>
+	<dict>
+		<key>NSStringLocalizedFormatKey</key>
+		<string>%#@count@</string>
+		<key>count</key>
+		<dict>
+			<key>NSStringFormatSpecTypeKey</key>
+			<string>NSStringPluralRuleType</string>
+			<key>NSStringFormatValueTypeKey</key>
+			<string>d</string>
+			<key>one</key>
+			<string>%d item</string>
+			<key>other</key>
+			<string>%d items</string>
+		</dict>
+	</dict>
+</dict>
+</plist>
+`,
+	}
+	for name, expected := range expectedFiles {
+		actual, err := readFile(filepath.Join(root, name))
+		if err != nil {
+			t.Errorf("%v\n", err)
+		} else if actual != expected {
+			t.Errorf("%v: %v\n", name, actual)
+		}
+	}
+	if _, err := os.Stat(catalogPath); !os.IsNotExist(err) {
+		t.Errorf("%v\n", err)
+	}
+}
+
+func TestConvertUnrepresentable(t *testing.T) {
+	root, err := ioutil.TempDir("", "gogenstrings")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	defer os.RemoveAll(root)
+
+	writeFiles(t, root, map[string]string{
+		"en.lproj/Localizable.strings": "/* A */\n\"a\" = \"a\";\n",
+		"ja.lproj/Localizable.strings": "/* エー */\n\"a\" = \"エー\";\n",
+	})
+	ctx := newGenstringsContext(genstringsOptions{
+		rootPath:    root,
+		devlang:     "en",
+		routineName: "NSLocalizedString",
+	})
+	err = ctx.convert(convertToStringCatalog, true)
+	jaPath := filepath.Join(root, "ja.lproj") + "/Localizable.strings"
+	if err == nil || err.Error() != jaPath+":2:1: comment of `a` differs from en.lproj" {
+		t.Errorf("%v\n", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "Localizable.xcstrings")); !os.IsNotExist(err) {
+		t.Errorf("%v\n", err)
+	}
+
+	os.RemoveAll(filepath.Join(root, "en.lproj"))
+	os.RemoveAll(filepath.Join(root, "ja.lproj"))
+	writeFiles(t, root, map[string]string{
+		"Localizable.xcstrings": `{
+  "sourceLanguage" : "en",
+  "strings" : {
+    "a" : {
+      "localizations" : {
+        "en" : {
+          "variations" : {
+            "device" : {
+              "iphone" : {
+                "stringUnit" : {
+                  "state" : "translated",
+                  "value" : "a"
+                }
+              }
+            }
+          }
+        }
+      }
+    }
+  },
+  "version" : "1.0"
+}
+`,
+	})
+	ctx = newGenstringsContext(genstringsOptions{
+		rootPath:    root,
+		devlang:     "en",
+		routineName: "NSLocalizedString",
+	})
+	err = ctx.convert(convertToDotStrings, true)
+	if err == nil || err.Error() != filepath.Join(root, "Localizable.xcstrings")+": `a` varies by device in en" {
+		t.Errorf("%v\n", err)
+	}
+}
+
+func TestConvertKeepsOriginals(t *testing.T) {
+	root, err := ioutil.TempDir("", "gogenstrings")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	defer os.RemoveAll(root)
+
+	files := map[string]string{
+		"en.lproj/Localizable.strings": "/* A */\n\"a\" = \"a\";\n",
+		"en.lproj/InfoPlist.strings":   "\"CFBundleName\" = \"A\";\n",
+		"Pods/X/en.lproj/X.strings":    "\"x\" = \"x\";\n",
+		"Pods/X/ja.lproj/X.strings":    "\"x\" = \"エックス\";\n",
+		"Pods/Y/en.lproj/Y.strings":    "\"y\" = \"y\";\n",
+	}
+	writeFiles(t, root, files)
+
+	// lprojs of the same language in different directories
+	// cannot be merged.
+	ctx := newGenstringsContext(genstringsOptions{
+		rootPath:    root,
+		devlang:     "en",
+		routineName: "NSLocalizedString",
+	})
+	err = ctx.convert(convertToStringCatalog, false)
+	if err == nil || !strings.Contains(err.Error(), "use -exclude to convert one directory at a time") {
+		t.Errorf("%v\n", err)
+	}
+
+	ctx = newGenstringsContext(genstringsOptions{
+		rootPath:      root,
+		devlang:       "en",
+		routineName:   "NSLocalizedString",
+		excludeRegexp: regexp.MustCompile(`/Pods/`),
+	})
+	if err := ctx.convert(convertToStringCatalog, false); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	for _, name := range []string{"Localizable.xcstrings", "InfoPlist.xcstrings"} {
+		if _, err := os.Stat(filepath.Join(root, name)); err != nil {
+			t.Errorf("%v: %v\n", name, err)
+		}
+	}
+	for _, name := range []string{"Pods/X/X.xcstrings", "Pods/Y/Y.xcstrings"} {
+		if _, err := os.Stat(filepath.Join(root, name)); !os.IsNotExist(err) {
+			t.Errorf("%v: %v\n", name, err)
+		}
+	}
+	for name, expected := range files {
+		if actual, err := readFile(filepath.Join(root, name)); err != nil || actual != expected {
+			t.Errorf("%v: %v %v\n", name, actual, err)
+		}
+	}
+}
+
+func TestPluralFromStringsdictPositional(t *testing.T) {
+	em, err := parseStringsdict(stringsdictHeader+`<dict>
+	<key>a</key>
+	<dict>
+		<key>NSStringLocalizedFormatKey</key>
+		<string>%1$#@files@ in %2$#@folders@</string>
+		<key>files</key>
+		<dict>
+			<key>NSStringFormatSpecTypeKey</key>
+			<string>NSStringPluralRuleType</string>
+			<key>NSStringFormatValueTypeKey</key>
+			<string>d</string>
+			<key>other</key>
+			<string>%1$d files</string>
+		</dict>
+		<key>folders</key>
+		<dict>
+			<key>NSStringFormatSpecTypeKey</key>
+			<string>NSStringPluralRuleType</string>
+			<key>NSStringFormatValueTypeKey</key>
+			<string>d</string>
+			<key>other</key>
+			<string>%2$d folders</string>
+		</dict>
+	</dict>
+</dict>
+</plist>
+`, "Localizable.stringsdict")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	l, err := pluralFromStringsdict(em["a"])
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	for _, name := range []string{"files", "folders"} {
+		actual := l.Substitutions[name].Variations["plural"]["other"].StringUnit.Value
+		if expected := substitutionArg + " " + name; actual != expected {
+			t.Errorf("%v: %v\n", name, actual)
+		}
+	}
+}
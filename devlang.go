@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/iawaknahc/gogenstrings/errors"
+	"github.com/iawaknahc/gogenstrings/linecol"
+	"github.com/iawaknahc/gogenstrings/xmlplist"
+)
+
+const (
+	defaultDevlang             = "en"
+	packageSwiftBasename       = "Package.swift"
+	developmentLanguageSetting = "$(DEVELOPMENT_LANGUAGE)"
+)
+
+var defaultLocalizationRe = regexp.MustCompile(`^defaultLocalization\s*:\s*"([^"]*)"`)
+
+// devlangSource is where the development language comes from.
+type devlangSource struct {
+	filepath string
+	line     int
+	col      int
+	devlang  string
+}
+
+// parseDefaultLocalization returns defaultLocalization of Package.swift.
+func parseDefaultLocalization(src, filepath string) (devlangSource, bool) {
+	for i := 0; i < len(src); {
+		// defaultLocalization in comments and string literals is ignored.
+		if j := skipCommentOrString(src, i); j > i {
+			i = j
+			continue
+		}
+		loc := defaultLocalizationRe.FindStringSubmatchIndex(src[i:])
+		if loc == nil {
+			i++
+			continue
+		}
+		lineColer := linecol.NewLineColer(src)
+		line, col := lineColer.LineCol(i + loc[2])
+		return devlangSource{
+			filepath: filepath,
+			line:     line,
+			col:      col,
+			devlang:  src[i+loc[2] : i+loc[3]],
+		}, true
+	}
+	return devlangSource{}, false
+}
+
+// parseDevelopmentRegion returns CFBundleDevelopmentRegion of Info.plist.
+func parseDevelopmentRegion(src, filepath string) (devlangSource, bool, error) {
+	value, err := xmlplist.ParseXMLPlist(src, filepath)
+	if err != nil {
+		return devlangSource{}, false, err
+	}
+	dict, ok := value.Value.(xmlplist.Dict)
+	if !ok {
+		return devlangSource{}, false, nil
+	}
+	region, ok := dict.Get("CFBundleDevelopmentRegion")
+	if !ok {
+		return devlangSource{}, false, nil
+	}
+	s, ok := region.Value.(string)
+	if !ok {
+		return devlangSource{}, false, nil
+	}
+	return devlangSource{
+		filepath: filepath,
+		line:     region.Line,
+		col:      region.Col,
+		devlang:  s,
+	}, true, nil
+}
+
+// devlangSources returns the development language
+// in Info.plist, project.pbxproj and Package.swift.
+func (p *genstringsContext) devlangSources() ([]devlangSource, error) {
+	out := []devlangSource{}
+
+	var projectSource *devlangSource
+	if p.xcodeprojPath != "" {
+		proj, err := p.readPBXProj()
+		if err != nil {
+			return nil, err
+		}
+		if region := proj.developmentRegion(); region != "" {
+			projectSource = &devlangSource{
+				filepath: proj.filepath,
+				devlang:  region,
+			}
+		}
+	}
+
+	if p.infoPlistPath != "" {
+		content, err := readFile(p.infoPlistPath)
+		if err != nil {
+			return nil, err
+		}
+		source, ok, err := parseDevelopmentRegion(content, p.infoPlistPath)
+		if err != nil {
+			return nil, err
+		}
+		// $(DEVELOPMENT_LANGUAGE) is the developmentRegion of the project.
+		if ok && source.devlang == developmentLanguageSetting {
+			ok = projectSource != nil
+			if ok {
+				source.devlang = projectSource.devlang
+			}
+		}
+		if ok {
+			out = append(out, source)
+		}
+	}
+
+	if projectSource != nil {
+		out = append(out, *projectSource)
+	}
+
+	packageSwiftPath := filepath.Join(p.rootPath, packageSwiftBasename)
+	content, err := readFile(packageSwiftPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+	} else if source, ok := parseDefaultLocalization(content, packageSwiftPath); ok {
+		out = append(out, source)
+	}
+
+	return out, nil
+}
+
+// resolveDevlang derives the development language
+// if it is not given. Every source must agree.
+func (p *genstringsContext) resolveDevlang() error {
+	if p.devlang != "" {
+		return nil
+	}
+	sources, err := p.devlangSources()
+	if err != nil {
+		return err
+	}
+	if len(sources) <= 0 {
+		p.devlang = defaultDevlang
+		return nil
+	}
+	first := sources[0]
+	errs := errors.List{}
+	for _, source := range sources[1:] {
+		if source.devlang != first.devlang {
+			errs = append(errs, fileLineColErr(
+				source.filepath,
+				source.line,
+				source.col,
+				fmt.Sprintf(
+					"development language `%v` differs from `%v` in %v",
+					source.devlang,
+					first.devlang,
+					first.filepath,
+				),
+			))
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	p.devlang = first.devlang
+	return nil
+}
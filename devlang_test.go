@@ -0,0 +1,146 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func infoPlistWithDevelopmentRegion(region string) string {
+	return stringsdictHeader + `<dict>
+	<key>CFBundleDevelopmentRegion</key>
+	<string>` + region + `</string>
+</dict>
+</plist>
+`
+}
+
+func TestParseDefaultLocalization(t *testing.T) {
+	src := `// swift-tools-version:5.3
+import PackageDescription
+
+let package = Package(
+    name: "Foo",
+    defaultLocalization: "ja",
+    targets: []
+)
+`
+	source, ok := parseDefaultLocalization(src, "Package.swift")
+	if !ok || source.devlang != "ja" || source.line != 6 || source.col != 27 {
+		t.Errorf("%+v\n", source)
+	}
+	if _, ok := parseDefaultLocalization(`let package = Package(name: "Foo")`, "Package.swift"); ok {
+		t.Fail()
+	}
+
+	// Comments and string literals are ignored.
+	src = `// swift-tools-version:5.3
+import PackageDescription
+
+let package = Package(
+    name: "defaultLocalization: \"de\"",
+    // defaultLocalization: "fr",
+    /* defaultLocalization: "es", */
+    defaultLocalization: "ja",
+    targets: []
+)
+`
+	source, ok = parseDefaultLocalization(src, "Package.swift")
+	if !ok || source.devlang != "ja" || source.line != 8 || source.col != 27 {
+		t.Errorf("%+v\n", source)
+	}
+	if _, ok := parseDefaultLocalization(`// defaultLocalization: "fr"`, "Package.swift"); ok {
+		t.Fail()
+	}
+}
+
+func TestResolveDevlang(t *testing.T) {
+	root, err := ioutil.TempDir("", "gogenstrings")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	defer os.RemoveAll(root)
+
+	infoPlistPath := filepath.Join(root, "Info.plist")
+	packageSwiftPath := filepath.Join(root, "Package.swift")
+
+	// Default
+	ctx := newGenstringsContext(genstringsOptions{
+		rootPath:    root,
+		routineName: "NSLocalizedString",
+	})
+	if err := ctx.resolveDevlang(); err != nil || ctx.devlang != "en" {
+		t.Errorf("%v %v\n", ctx.devlang, err)
+	}
+
+	// $(DEVELOPMENT_LANGUAGE) is resolved against the project.
+	writeFiles(t, root, map[string]string{
+		"Info.plist":                    infoPlistWithDevelopmentRegion("$(DEVELOPMENT_LANGUAGE)"),
+		"App.xcodeproj/project.pbxproj": testPBXProj,
+	})
+	ctx = newGenstringsContext(genstringsOptions{
+		rootPath:      root,
+		routineName:   "NSLocalizedString",
+		infoPlistPath: infoPlistPath,
+		xcodeprojPath: filepath.Join(root, "App.xcodeproj"),
+	})
+	if err := ctx.resolveDevlang(); err != nil || ctx.devlang != "en" {
+		t.Errorf("%v %v\n", ctx.devlang, err)
+	}
+
+	// Sources disagree.
+	writeFiles(t, root, map[string]string{
+		"Info.plist":    infoPlistWithDevelopmentRegion("ja"),
+		"Package.swift": `let package = Package(name: "Foo", defaultLocalization: "en")`,
+	})
+	ctx = newGenstringsContext(genstringsOptions{
+		rootPath:      root,
+		routineName:   "NSLocalizedString",
+		infoPlistPath: infoPlistPath,
+	})
+	err = ctx.resolveDevlang()
+	expected := packageSwiftPath + ":1:58: development language `en` differs from `ja` in " + infoPlistPath
+	if err == nil || err.Error() != expected {
+		t.Errorf("%v\n", err)
+	}
+
+	// -devlang is not derived.
+	ctx = newGenstringsContext(genstringsOptions{
+		rootPath:      root,
+		devlang:       "fr",
+		routineName:   "NSLocalizedString",
+		infoPlistPath: infoPlistPath,
+	})
+	if err := ctx.resolveDevlang(); err != nil || ctx.devlang != "fr" {
+		t.Errorf("%v %v\n", ctx.devlang, err)
+	}
+}
+
+func TestResolveDevlangWithStringCatalog(t *testing.T) {
+	root, err := ioutil.TempDir("", "gogenstrings")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	defer os.RemoveAll(root)
+
+	infoPlistPath := filepath.Join(root, "Info.plist")
+	packageSwiftPath := filepath.Join(root, "Package.swift")
+	writeFiles(t, root, map[string]string{
+		"Info.plist":            infoPlistWithDevelopmentRegion("en"),
+		"Package.swift":         `let package = Package(name: "Foo", defaultLocalization: "ja")`,
+		"Localizable.xcstrings": `{"sourceLanguage": "en", "strings": {}, "version": "1.0"}`,
+	})
+
+	// A String Catalog does not hide the disagreement.
+	ctx := newGenstringsContext(genstringsOptions{
+		rootPath:      root,
+		routineName:   "NSLocalizedString",
+		infoPlistPath: infoPlistPath,
+	})
+	err = ctx.find()
+	expected := packageSwiftPath + ":1:58: development language `ja` differs from `en` in " + infoPlistPath
+	if err == nil || err.Error() != expected {
+		t.Errorf("%v\n", err)
+	}
+}
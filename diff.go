@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+const diffContextLines = 3
+
+type diffOpKind int
+
+const (
+	diffEqual diffOpKind = iota
+	diffDelete
+	diffInsert
+)
+
+type diffOp struct {
+	kind diffOpKind
+	line string
+}
+
+func (v diffOp) String() string {
+	prefix := " "
+	switch v.kind {
+	case diffDelete:
+		prefix = "-"
+	case diffInsert:
+		prefix = "+"
+	}
+	if strings.HasSuffix(v.line, "\n") {
+		return prefix + v.line
+	}
+	return prefix + v.line + "\n\\ No newline at end of file\n"
+}
+
+// splitLines splits s into lines keeping the line terminator.
+func splitLines(s string) []string {
+	out := []string{}
+	for s != "" {
+		i := strings.IndexByte(s, '\n')
+		if i < 0 {
+			out = append(out, s)
+			break
+		}
+		out = append(out, s[:i+1])
+		s = s[i+1:]
+	}
+	return out
+}
+
+// diffLines computes the shortest edit script from a to b.
+// See "An O(ND) Difference Algorithm and Its Variations" by Eugene W. Myers.
+func diffLines(a, b []string) []diffOp {
+	n := len(a)
+	m := len(b)
+	max := n + m
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	trace := [][]int{}
+
+Loop:
+	for d := 0; d <= max; d++ {
+		snapshot := make([]int, len(v))
+		copy(snapshot, v)
+		trace = append(trace, snapshot)
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				break Loop
+			}
+		}
+	}
+
+	// Backtrack
+	reversed := []diffOp{}
+	x := n
+	y := m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			reversed = append(reversed, diffOp{diffEqual, a[x-1]})
+			x--
+			y--
+		}
+		if d > 0 {
+			if x == prevX {
+				reversed = append(reversed, diffOp{diffInsert, b[y-1]})
+				y--
+			} else {
+				reversed = append(reversed, diffOp{diffDelete, a[x-1]})
+				x--
+			}
+		}
+	}
+
+	out := make([]diffOp, len(reversed))
+	for i, op := range reversed {
+		out[len(reversed)-1-i] = op
+	}
+	return out
+}
+
+func formatHunkRange(start, length int) string {
+	if length == 0 {
+		// GNU diff refers to the line before an empty range.
+		return fmt.Sprintf("%v,0", start-1)
+	}
+	if length == 1 {
+		return fmt.Sprintf("%v", start)
+	}
+	return fmt.Sprintf("%v,%v", start, length)
+}
+
+// unifiedDiff returns the unified diff from a to b.
+// It returns empty string if a and b are the same.
+func unifiedDiff(fromPath, toPath, a, b string) string {
+	if a == b {
+		return ""
+	}
+	ops := diffLines(splitLines(a), splitLines(b))
+
+	buf := bytes.Buffer{}
+	buf.WriteString("--- " + fromPath + "\n")
+	buf.WriteString("+++ " + toPath + "\n")
+
+	i := 0
+	// The 1-based line number of ops[i] in a and b
+	lineA := 1
+	lineB := 1
+	for i < len(ops) {
+		// Find the next change
+		if ops[i].kind == diffEqual {
+			i++
+			lineA++
+			lineB++
+			continue
+		}
+
+		// Include leading context
+		start := i - diffContextLines
+		if start < 0 {
+			start = 0
+		}
+		startA := lineA - (i - start)
+		startB := lineB - (i - start)
+
+		// Extend the hunk until there are enough unchanged lines
+		end := i
+		for end < len(ops) {
+			if ops[end].kind != diffEqual {
+				end++
+				continue
+			}
+			run := 0
+			for end+run < len(ops) && ops[end+run].kind == diffEqual {
+				run++
+			}
+			if end+run >= len(ops) || run > 2*diffContextLines {
+				if run > diffContextLines {
+					run = diffContextLines
+				}
+				end += run
+				break
+			}
+			end += run
+		}
+
+		lengthA := 0
+		lengthB := 0
+		hunk := bytes.Buffer{}
+		for _, op := range ops[start:end] {
+			switch op.kind {
+			case diffEqual:
+				lengthA++
+				lengthB++
+			case diffDelete:
+				lengthA++
+			case diffInsert:
+				lengthB++
+			}
+			hunk.WriteString(op.String())
+		}
+		fmt.Fprintf(
+			&buf,
+			"@@ -%v +%v @@\n",
+			formatHunkRange(startA, lengthA),
+			formatHunkRange(startB, lengthB),
+		)
+		buf.Write(hunk.Bytes())
+
+		// Advance past the hunk
+		for ; i < end; i++ {
+			switch ops[i].kind {
+			case diffEqual:
+				lineA++
+				lineB++
+			case diffDelete:
+				lineA++
+			case diffInsert:
+				lineB++
+			}
+		}
+	}
+	return buf.String()
+}
+
+// encodingDiff describes a change of encoding
+// which the unified diff cannot show.
+func encodingDiff(path string, from, to encoding) string {
+	return fmt.Sprintf("encoding of %v changes from %v to %v\n", path, from, to)
+}
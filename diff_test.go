@@ -0,0 +1,76 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	cases := []struct {
+		a        string
+		b        string
+		expected string
+	}{
+		{"", "", ""},
+		{"a\n", "a\n", ""},
+		{
+			"",
+			"a\n",
+			`--- from
++++ to
+@@ -0,0 +1 @@
++a
+`,
+		},
+		{
+			"/* old */\n\"key\" = \"value\";\n\n",
+			"/* new */\n\"key\" = \"value\";\n\n",
+			`--- from
++++ to
+@@ -1,3 +1,3 @@
+-/* old */
++/* new */
+ "key" = "value";
+ 
+`,
+		},
+		{
+			"1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			"1\n2\n3\nx\n5\n6\n7\n8\n9\n10\n11\ny\n",
+			`--- from
++++ to
+@@ -1,7 +1,7 @@
+ 1
+ 2
+ 3
+-4
++x
+ 5
+ 6
+ 7
+@@ -9,4 +9,4 @@
+ 9
+ 10
+ 11
+-12
++y
+`,
+		},
+		{
+			"a",
+			"b\n",
+			`--- from
++++ to
+@@ -1 +1 @@
+-a
+\ No newline at end of file
++b
+`,
+		},
+	}
+	for _, c := range cases {
+		actual := unifiedDiff("from", "to", c.a, c.b)
+		if actual != c.expected {
+			t.Errorf("%v\n", actual)
+		}
+	}
+}
@@ -48,6 +48,15 @@ a = "b";
 	}
 }
 
+func TestParseDotStringsEmpty(t *testing.T) {
+	for _, input := range []string{"", "\n", "/* comment */\n"} {
+		actual, err := parseDotStrings(input, "")
+		if err != nil || len(actual) != 0 {
+			t.Errorf("%q: %v %v\n", input, actual, err)
+		}
+	}
+}
+
 func TestParseDotStringsInvalid(t *testing.T) {
 	cases := []struct {
 		input string
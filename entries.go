@@ -28,18 +28,25 @@ func (p entries) print(suppressEmptyComment bool) string {
 	return buf.String()
 }
 
+// toEntryMap reports every duplicated key.
+// The first occurrence of the key is kept.
 func (p entries) toEntryMap() (entryMap, error) {
 	em := entryMap{}
+	errs := errors.List{}
 	for _, e := range p {
 		if _, ok := em[e.key]; ok {
-			return nil, errors.FileLineCol(
+			errs = append(errs, errors.FileLineCol(
 				e.filepath,
 				e.startLine,
 				e.startCol,
 				fmt.Sprintf("duplicated key `%v`", e.key),
-			)
+			).WithRule(ruleDuplicatedKey))
+			continue
 		}
 		em[e.key] = e
 	}
+	if len(errs) > 0 {
+		return em, errs
+	}
 	return em, nil
 }
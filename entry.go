@@ -4,6 +4,9 @@ import (
 	"strings"
 )
 
+// noCommentProvided is the comment of a routine call without comment.
+const noCommentProvided = "No comment provided by engineer."
+
 type entry struct {
 	filepath  string
 	startLine int
@@ -14,7 +17,7 @@ type entry struct {
 }
 
 func newEntryFromRoutineCall(rc routineCall) entry {
-	comment := "No comment provided by engineer."
+	comment := noCommentProvided
 	value := ""
 	if rc.comment != "" {
 		value = rc.comment
@@ -22,6 +25,10 @@ func newEntryFromRoutineCall(rc routineCall) entry {
 	} else {
 		value = rc.key
 	}
+	// The default value in source code takes precedence.
+	if rc.value != "" {
+		value = rc.value
+	}
 	ls := entry{
 		comment: comment,
 		key:     rc.key,
@@ -33,7 +40,7 @@ func newEntryFromRoutineCall(rc routineCall) entry {
 func (ls entry) mergeCall(rc routineCall) entry {
 	ls.comment = rc.comment
 	if ls.comment == "" {
-		ls.comment = "No comment provided by engineer."
+		ls.comment = noCommentProvided
 	}
 	return ls
 }
@@ -19,6 +19,13 @@ func TestNewEntryFromRoutineCall(t *testing.T) {
 	if e.value != "Default Value" {
 		t.Fail()
 	}
+
+	call.value = "Value"
+
+	e = newEntryFromRoutineCall(call)
+	if e.value != "Value" || e.comment != "Default Value" {
+		t.Fail()
+	}
 }
 
 func TestEntryMergeCall(t *testing.T) {
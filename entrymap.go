@@ -34,6 +34,23 @@ func (p entryMap) mergeDev(dev entryMap) entryMap {
 	return output
 }
 
+func (p entryMap) mergeInfoPlist(infoPlist entryMap) entryMap {
+	output := entryMap{}
+	// Copy existing entry if they are still in Info.plist
+	for key, entry := range p {
+		if _, ok := infoPlist[key]; ok {
+			output[key] = entry
+		}
+	}
+	// Copy new key in Info.plist
+	for key, infoPlistEntry := range infoPlist {
+		if _, ok := output[key]; !ok {
+			output[key] = infoPlistEntry
+		}
+	}
+	return output
+}
+
 func (p entryMap) toEntries() entries {
 	out := entries{}
 	for _, entry := range p {
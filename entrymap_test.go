@@ -112,3 +112,40 @@ func TestEntryMapToEntries(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestEntryMapMergeInfoPlist(t *testing.T) {
+	em := entryMap{
+		"CFBundleDisplayName": entry{
+			key:   "CFBundleDisplayName",
+			value: "Old Name",
+		},
+		"NSCameraUsageDescription": entry{
+			key:   "NSCameraUsageDescription",
+			value: "Use camera",
+		},
+	}
+	infoPlist := entryMap{
+		"CFBundleDisplayName": entry{
+			key:   "CFBundleDisplayName",
+			value: "New Name",
+		},
+		"NSMicrophoneUsageDescription": entry{
+			key:   "NSMicrophoneUsageDescription",
+			value: "Use microphone",
+		},
+	}
+	expected := entryMap{
+		"CFBundleDisplayName": entry{
+			key:   "CFBundleDisplayName",
+			value: "Old Name",
+		},
+		"NSMicrophoneUsageDescription": entry{
+			key:   "NSMicrophoneUsageDescription",
+			value: "Use microphone",
+		},
+	}
+	actual := em.mergeInfoPlist(infoPlist)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fail()
+	}
+}
@@ -2,13 +2,20 @@ package errors
 
 import (
 	"fmt"
+	"os"
+	"sort"
+	"strings"
 )
 
 // ErrFileLineCol tells which file, which line and which column has an error.
+// The end position is optional.
 type ErrFileLineCol struct {
 	filepath string
 	line     int
 	col      int
+	endLine  int
+	endCol   int
+	rule     string
 	message  string
 }
 
@@ -26,9 +33,23 @@ func FileLineCol(filepath string, line, col int, message string) ErrFileLineCol
 	}
 }
 
+// WithEnd returns a copy of e with the exclusive end position.
+func (e ErrFileLineCol) WithEnd(endLine, endCol int) ErrFileLineCol {
+	e.endLine = endLine
+	e.endCol = endCol
+	return e
+}
+
+// WithRule returns a copy of e with the rule ID.
+func (e ErrFileLineCol) WithRule(rule string) ErrFileLineCol {
+	e.rule = rule
+	return e
+}
+
 // ErrFile tells which file has an error.
 type ErrFile struct {
 	filepath string
+	rule     string
 	message  string
 }
 
@@ -43,3 +64,79 @@ func File(filepath, message string) ErrFile {
 		message:  message,
 	}
 }
+
+// WithRule returns a copy of e with the rule ID.
+func (e ErrFile) WithRule(rule string) ErrFile {
+	e.rule = rule
+	return e
+}
+
+// List is a list of errors.
+type List []error
+
+func (e List) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// Diagnostics collects errors.
+// The zero value is ready to use.
+type Diagnostics struct {
+	errs List
+}
+
+// Add adds err. List is flattened.
+func (d *Diagnostics) Add(err error) {
+	if err == nil {
+		return
+	}
+	if list, ok := err.(List); ok {
+		for _, err := range list {
+			d.Add(err)
+		}
+		return
+	}
+	d.errs = append(d.errs, err)
+}
+
+// Len returns the number of errors.
+func (d *Diagnostics) Len() int {
+	return len(d.errs)
+}
+
+// Err returns the errors sorted by file, line and col.
+// It returns nil if there is no error.
+func (d *Diagnostics) Err() error {
+	if len(d.errs) <= 0 {
+		return nil
+	}
+	out := make(List, len(d.errs))
+	copy(out, d.errs)
+	sort.SliceStable(out, func(i, j int) bool {
+		fi, li, ci := position(out[i])
+		fj, lj, cj := position(out[j])
+		if fi != fj {
+			return fi < fj
+		}
+		if li != lj {
+			return li < lj
+		}
+		return ci < cj
+	})
+	return out
+}
+
+func position(err error) (filepath string, line, col int) {
+	switch e := err.(type) {
+	case ErrFileLineCol:
+		return e.filepath, e.line, e.col
+	case ErrFile:
+		return e.filepath, 0, 0
+	case *os.PathError:
+		return e.Path, 0, 0
+	}
+	return "", 0, 0
+}
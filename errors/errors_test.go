@@ -0,0 +1,31 @@
+package errors
+
+import (
+	"testing"
+)
+
+func TestDiagnostics(t *testing.T) {
+	d := Diagnostics{}
+	if d.Err() != nil {
+		t.Fail()
+	}
+
+	d.Add(nil)
+	d.Add(FileLineCol("b", 2, 1, "c"))
+	d.Add(List{
+		FileLineCol("b", 1, 2, "b"),
+		File("a", "a"),
+	})
+	d.Add(FileLineCol("b", 1, 1, "a"))
+	if d.Len() != 4 {
+		t.Fail()
+	}
+
+	expected := `a: a
+b:1:1: a
+b:1:2: b
+b:2:1: c`
+	if err := d.Err(); err == nil || err.Error() != expected {
+		t.Errorf("%v\n", err)
+	}
+}
@@ -0,0 +1,229 @@
+package errors
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Default rule IDs of errors without explicit rule.
+const (
+	RuleSyntax = "syntax"
+	RuleFile   = "file"
+	RuleIO     = "io"
+	RuleError  = "error"
+)
+
+// SeverityError is the only severity reported for now.
+const SeverityError = "error"
+
+// Diagnostic is the structured form of an error.
+// Line and col are 1-based. End position is exclusive
+// and is zero if unknown.
+type Diagnostic struct {
+	RuleID   string `json:"ruleId"`
+	Severity string `json:"severity"`
+	Filepath string `json:"file,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Col      int    `json:"column,omitempty"`
+	EndLine  int    `json:"endLine,omitempty"`
+	EndCol   int    `json:"endColumn,omitempty"`
+	Message  string `json:"message"`
+}
+
+// ToDiagnostics converts err into diagnostics. List is flattened.
+// It returns empty slice if err is nil.
+func ToDiagnostics(err error) []Diagnostic {
+	out := []Diagnostic{}
+	if err == nil {
+		return out
+	}
+	if list, ok := err.(List); ok {
+		for _, err := range list {
+			out = append(out, ToDiagnostics(err)...)
+		}
+		return out
+	}
+	return append(out, toDiagnostic(err))
+}
+
+func toDiagnostic(err error) Diagnostic {
+	switch e := err.(type) {
+	case ErrFileLineCol:
+		return Diagnostic{
+			RuleID:   ruleOrDefault(e.rule, RuleSyntax),
+			Severity: SeverityError,
+			Filepath: e.filepath,
+			Line:     e.line,
+			Col:      e.col,
+			EndLine:  e.endLine,
+			EndCol:   e.endCol,
+			Message:  e.message,
+		}
+	case ErrFile:
+		return Diagnostic{
+			RuleID:   ruleOrDefault(e.rule, RuleFile),
+			Severity: SeverityError,
+			Filepath: e.filepath,
+			Message:  e.message,
+		}
+	case *os.PathError:
+		return Diagnostic{
+			RuleID:   RuleIO,
+			Severity: SeverityError,
+			Filepath: e.Path,
+			Message:  e.Op + ": " + e.Err.Error(),
+		}
+	}
+	return Diagnostic{
+		RuleID:   RuleError,
+		Severity: SeverityError,
+		Message:  err.Error(),
+	}
+}
+
+func ruleOrDefault(rule, defaultRule string) string {
+	if rule == "" {
+		return defaultRule
+	}
+	return rule
+}
+
+// WriteJSON writes diagnostics as a JSON array.
+func WriteJSON(w io.Writer, diagnostics []Diagnostic) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(diagnostics)
+}
+
+const sarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+const sarifVersion = "2.1.0"
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri,omitempty"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID string `json:"id"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+	EndLine     int `json:"endLine,omitempty"`
+	EndColumn   int `json:"endColumn,omitempty"`
+}
+
+// WriteSARIF writes diagnostics as a SARIF 2.1.0 log of a single run.
+func WriteSARIF(w io.Writer, diagnostics []Diagnostic, toolName, informationURI string) error {
+	ruleIDs := []string{}
+	seen := map[string]bool{}
+	for _, d := range diagnostics {
+		if !seen[d.RuleID] {
+			seen[d.RuleID] = true
+			ruleIDs = append(ruleIDs, d.RuleID)
+		}
+	}
+	sort.Strings(ruleIDs)
+	rules := make([]sarifRule, len(ruleIDs))
+	ruleIndex := map[string]int{}
+	for i, id := range ruleIDs {
+		rules[i] = sarifRule{ID: id}
+		ruleIndex[id] = i
+	}
+
+	results := make([]sarifResult, len(diagnostics))
+	for i, d := range diagnostics {
+		result := sarifResult{
+			RuleID:    d.RuleID,
+			RuleIndex: ruleIndex[d.RuleID],
+			Level:     d.Severity,
+			Message:   sarifMessage{d.Message},
+		}
+		if d.Filepath != "" {
+			loc := sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{
+					URI: (&url.URL{Path: filepath.ToSlash(d.Filepath)}).String(),
+				},
+			}
+			// SARIF lines are 1-based so 0 means unknown.
+			if d.Line > 0 {
+				loc.Region = &sarifRegion{
+					StartLine:   d.Line,
+					StartColumn: d.Col,
+				}
+				if d.EndLine > 0 {
+					loc.Region.EndLine = d.EndLine
+					loc.Region.EndColumn = d.EndCol
+				}
+			}
+			result.Locations = []sarifLocation{{loc}}
+		}
+		results[i] = result
+	}
+
+	log := sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs: []sarifRun{
+			{
+				Tool: sarifTool{
+					Driver: sarifDriver{
+						Name:           toolName,
+						InformationURI: informationURI,
+						Rules:          rules,
+					},
+				},
+				Results: results,
+			},
+		},
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(log)
+}
@@ -0,0 +1,144 @@
+package errors
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestToDiagnostics(t *testing.T) {
+	if d := ToDiagnostics(nil); len(d) != 0 {
+		t.Fail()
+	}
+
+	err := List{
+		FileLineCol("a", 1, 2, "a").WithEnd(1, 5),
+		FileLineCol("a", 3, 4, "b").WithRule("duplicated-key"),
+		File("b", "c"),
+		&os.PathError{Op: "open", Path: "c", Err: os.ErrNotExist},
+	}
+	expected := []Diagnostic{
+		{RuleSyntax, SeverityError, "a", 1, 2, 1, 5, "a"},
+		{"duplicated-key", SeverityError, "a", 3, 4, 0, 0, "b"},
+		{RuleFile, SeverityError, "b", 0, 0, 0, 0, "c"},
+		{RuleIO, SeverityError, "c", 0, 0, 0, 0, "open: file does not exist"},
+	}
+	actual := ToDiagnostics(err)
+	if len(actual) != len(expected) {
+		t.Fatalf("%v\n", actual)
+	}
+	for i, d := range actual {
+		if d != expected[i] {
+			t.Errorf("%v: %v\n", i, d)
+		}
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	buf := bytes.Buffer{}
+	if err := WriteJSON(&buf, ToDiagnostics(FileLineCol("a", 1, 2, "a").WithEnd(1, 5))); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	expected := `[
+  {
+    "ruleId": "syntax",
+    "severity": "error",
+    "file": "a",
+    "line": 1,
+    "column": 2,
+    "endLine": 1,
+    "endColumn": 5,
+    "message": "a"
+  }
+]
+`
+	if actual := buf.String(); actual != expected {
+		t.Errorf("%v\n", actual)
+	}
+
+	buf.Reset()
+	if err := WriteJSON(&buf, ToDiagnostics(nil)); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if actual := buf.String(); actual != "[]\n" {
+		t.Errorf("%v\n", actual)
+	}
+}
+
+func TestWriteSARIF(t *testing.T) {
+	err := List{
+		FileLineCol("dir/a b.swift", 1, 2, "a").WithEnd(1, 5),
+		File("c", "b").WithRule("outdated-file"),
+	}
+	buf := bytes.Buffer{}
+	if err := WriteSARIF(&buf, ToDiagnostics(err), "tool", ""); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	expected := `{
+  "$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+  "version": "2.1.0",
+  "runs": [
+    {
+      "tool": {
+        "driver": {
+          "name": "tool",
+          "rules": [
+            {
+              "id": "outdated-file"
+            },
+            {
+              "id": "syntax"
+            }
+          ]
+        }
+      },
+      "results": [
+        {
+          "ruleId": "syntax",
+          "ruleIndex": 1,
+          "level": "error",
+          "message": {
+            "text": "a"
+          },
+          "locations": [
+            {
+              "physicalLocation": {
+                "artifactLocation": {
+                  "uri": "dir/a%20b.swift"
+                },
+                "region": {
+                  "startLine": 1,
+                  "startColumn": 2,
+                  "endLine": 1,
+                  "endColumn": 5
+                }
+              }
+            }
+          ]
+        },
+        {
+          "ruleId": "outdated-file",
+          "ruleIndex": 0,
+          "level": "error",
+          "message": {
+            "text": "b"
+          },
+          "locations": [
+            {
+              "physicalLocation": {
+                "artifactLocation": {
+                  "uri": "c"
+                }
+              }
+            }
+          ]
+        }
+      ]
+    }
+  ]
+}
+`
+	if actual := buf.String(); actual != expected {
+		t.Errorf("%v\n", actual)
+	}
+}
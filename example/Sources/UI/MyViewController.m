@@ -1,2 +1,3 @@
 NSLocalizedString(@"phrase.dismiss", @"Dismiss")
 NSLocalizedString(@"home.greeting", @"Hello, \"\n %% %1$s")
+NSLocalizedStringFromTable(@"settings.title", @"Settings", @"Settings")
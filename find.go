@@ -45,3 +45,20 @@ func findSourceFiles(root string, exclude *regexp.Regexp) (output []string, oute
 	filepath.Walk(root, walkFn)
 	return
 }
+
+func findStringCatalogs(root string, exclude *regexp.Regexp) (output []string, outerr error) {
+	walkFn := func(fullpath string, info os.FileInfo, err error) error {
+		if err != nil {
+			outerr = err
+			return err
+		}
+		if info.Mode().IsRegular() && filepath.Ext(fullpath) == dotXCStringsExt {
+			if exclude == nil || !exclude.MatchString(fullpath) {
+				output = append(output, fullpath)
+			}
+		}
+		return nil
+	}
+	filepath.Walk(root, walkFn)
+	return
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// formatSpecifier is a printf or CFString format specifier
+// or the asterisk width or precision which consumes an argument.
+type formatSpecifier struct {
+	// position is the 1-based index of the consumed argument.
+	position int
+	// length is the length modifier, e.g. "l", "ll".
+	length string
+	// conversion is the conversion character, e.g. '@', 'd'.
+	conversion byte
+	// text is the specifier as written in the string.
+	text string
+}
+
+// argumentType tells which specifiers are compatible.
+// Signedness and notation are ignored
+// because they do not change the size of the argument.
+func (s formatSpecifier) argumentType() string {
+	length := s.length
+	// q is the BSD spelling of ll.
+	if length == "q" {
+		length = "ll"
+	}
+	switch s.conversion {
+	case 'd', 'i', 'u', 'x', 'X', 'o', 'c':
+		return length + "d"
+	case 'D', 'U', 'O':
+		return "ld"
+	case 'f', 'F', 'e', 'E', 'g', 'G', 'a', 'A':
+		return length + "f"
+	}
+	return length + string(s.conversion)
+}
+
+const formatFlags = "-+ #0'"
+const formatConversions = "@dDiuUxXoOfFeEgGaAcCsSp"
+
+var formatLengths = []string{"hh", "h", "ll", "l", "q", "L", "z", "t", "j"}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// scanDigits returns the end of the digits in s starting at i.
+func scanDigits(s string, i int) int {
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	return i
+}
+
+// scanPosition scans `n$` in s starting at i.
+// It returns 0 if there is none.
+func scanPosition(s string, i int) (position int, end int) {
+	j := scanDigits(s, i)
+	if j == i || j >= len(s) || s[j] != '$' {
+		return 0, i
+	}
+	position, _ = strconv.Atoi(s[i:j])
+	return position, j + 1
+}
+
+// parseFormatSpecifiers returns the specifiers in s sorted by position.
+// Text that is not a valid specifier is ignored like CFString does.
+func parseFormatSpecifiers(s string) ([]formatSpecifier, error) {
+	out := []formatSpecifier{}
+	positional := 0
+	sequential := 0
+	next := 1
+
+	add := func(spec formatSpecifier, position int) {
+		if position > 0 {
+			positional++
+			spec.position = position
+		} else {
+			sequential++
+			spec.position = next
+			next++
+		}
+		out = append(out, spec)
+	}
+
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' {
+			continue
+		}
+		start := i
+		i++
+		if i < len(s) && s[i] == '%' {
+			continue
+		}
+
+		var position int
+		position, i = scanPosition(s, i)
+		for i < len(s) && strings.IndexByte(formatFlags, s[i]) >= 0 {
+			i++
+		}
+
+		// The asterisk width and precision consume an argument.
+		stars := []formatSpecifier{}
+		starPositions := []int{}
+		scanStar := func(i int) int {
+			if i < len(s) && s[i] == '*' {
+				starPosition, end := scanPosition(s, i+1)
+				stars = append(stars, formatSpecifier{
+					conversion: 'd',
+					text:       s[i:end],
+				})
+				starPositions = append(starPositions, starPosition)
+				return end
+			}
+			return scanDigits(s, i)
+		}
+		i = scanStar(i)
+		if i < len(s) && s[i] == '.' {
+			i = scanStar(i + 1)
+		}
+
+		length := ""
+		for _, l := range formatLengths {
+			if strings.HasPrefix(s[i:], l) {
+				length = l
+				i += len(l)
+				break
+			}
+		}
+
+		if i >= len(s) || strings.IndexByte(formatConversions, s[i]) < 0 {
+			// Not a specifier
+			i = start
+			continue
+		}
+
+		for j, star := range stars {
+			add(star, starPositions[j])
+		}
+		add(formatSpecifier{
+			length:     length,
+			conversion: s[i],
+			text:       s[start : i+1],
+		}, position)
+	}
+
+	if positional > 0 && sequential > 0 {
+		return nil, fmt.Errorf("mixes positional and non-positional format specifiers")
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].position < out[j].position
+	})
+	for i := 1; i < len(out); i++ {
+		if out[i].position == out[i-1].position &&
+			out[i].argumentType() != out[i-1].argumentType() {
+			return nil, fmt.Errorf(
+				"uses argument %v as both `%v` and `%v`",
+				out[i].position,
+				out[i-1].text,
+				out[i].text,
+			)
+		}
+	}
+	return out, nil
+}
+
+// formatArguments maps the position of every argument
+// to the first specifier consuming it.
+func formatArguments(specs []formatSpecifier) map[int]formatSpecifier {
+	out := map[int]formatSpecifier{}
+	for _, spec := range specs {
+		if _, ok := out[spec.position]; !ok {
+			out[spec.position] = spec
+		}
+	}
+	return out
+}
+
+// compareFormatSpecifiers reports how value differs from devValue.
+// It returns empty string if they are compatible.
+func compareFormatSpecifiers(key, value, devValue, devLprojName string) string {
+	devSpecs, err := parseFormatSpecifiers(devValue)
+	if err != nil {
+		// Reported on the development language
+		return ""
+	}
+	specs, err := parseFormatSpecifiers(value)
+	if err != nil {
+		return fmt.Sprintf("`%v` %v", key, err)
+	}
+
+	devArgs := formatArguments(devSpecs)
+	args := formatArguments(specs)
+	if len(args) != len(devArgs) {
+		return fmt.Sprintf(
+			"`%v` has %v format argument(s) but %v has %v",
+			key,
+			len(args),
+			devLprojName,
+			len(devArgs),
+		)
+	}
+
+	positions := []int{}
+	for position := range devArgs {
+		positions = append(positions, position)
+	}
+	sort.Ints(positions)
+	for _, position := range positions {
+		devSpec := devArgs[position]
+		spec, ok := args[position]
+		if !ok {
+			return fmt.Sprintf(
+				"`%v` does not use argument %v but %v uses it as `%v`",
+				key,
+				position,
+				devLprojName,
+				devSpec.text,
+			)
+		}
+		if spec.argumentType() != devSpec.argumentType() {
+			return fmt.Sprintf(
+				"`%v` uses argument %v as `%v` but %v uses it as `%v`",
+				key,
+				position,
+				spec.text,
+				devLprojName,
+				devSpec.text,
+			)
+		}
+	}
+	return ""
+}
+
+// hasIntegerFormatSpecifier tells whether s formats an integer,
+// i.e. it can be a plural.
+func hasIntegerFormatSpecifier(s string) bool {
+	specs, err := parseFormatSpecifiers(s)
+	if err != nil {
+		return false
+	}
+	for _, spec := range specs {
+		switch spec.conversion {
+		case 'd', 'i', 'u', 'D', 'U':
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestParseFormatSpecifiers(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected string
+	}{
+		{"", ""},
+		{"100%% sure", ""},
+		{"100%", ""},
+		{"%@ liked your post", "1:%@"},
+		{"%d of %lld", "1:%d 2:%lld"},
+		{"%2$@ %1$@", "1:%1$@ 2:%2$@"},
+		{"%-10.2f", "1:%-10.2f"},
+		{"%*.*f", "1:* 2:* 3:%*.*f"},
+		{"%1$@ %1$@", "1:%1$@ 1:%1$@"},
+		{"%y", ""},
+		{"%qd %zu %hhx %C %S %s %p", "1:%qd 2:%zu 3:%hhx 4:%C 5:%S 6:%s 7:%p"},
+	}
+	for _, c := range cases {
+		specs, err := parseFormatSpecifiers(c.input)
+		if err != nil {
+			t.Errorf("%v: %v\n", c.input, err)
+			continue
+		}
+		actual := ""
+		for i, spec := range specs {
+			if i > 0 {
+				actual += " "
+			}
+			actual += fmt.Sprintf("%v:%v", spec.position, spec.text)
+		}
+		if actual != c.expected {
+			t.Errorf("%v: %v\n", c.input, actual)
+		}
+	}
+}
+
+func TestParseFormatSpecifiersInvalid(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected string
+	}{
+		{"%1$@ %@", "mixes positional and non-positional format specifiers"},
+		{"%1$@ %1$d", "uses argument 1 as both `%1$@` and `%1$d`"},
+	}
+	for _, c := range cases {
+		_, err := parseFormatSpecifiers(c.input)
+		if err == nil || err.Error() != c.expected {
+			t.Errorf("%v: %v\n", c.input, err)
+		}
+	}
+}
+
+func TestCompareFormatSpecifiers(t *testing.T) {
+	cases := []struct {
+		value    string
+		devValue string
+		expected string
+	}{
+		{"%@さんがいいねしました", "%@ liked your post", ""},
+		{"%2$@ %1$d", "%d %@", ""},
+		{"%lu", "%ld", ""},
+		{"%qd", "%lld", ""},
+		{"いいね", "%@ liked your post", "`k` has 0 format argument(s) but en.lproj has 1"},
+		{"%d", "%@", "`k` uses argument 1 as `%d` but en.lproj uses it as `%@`"},
+		{"%d", "%ld", "`k` uses argument 1 as `%d` but en.lproj uses it as `%ld`"},
+		{"%1$@ %3$@", "%@ %@", "`k` does not use argument 2 but en.lproj uses it as `%@`"},
+		{"%1$@ %@", "%@ %@", "`k` mixes positional and non-positional format specifiers"},
+	}
+	for _, c := range cases {
+		actual := compareFormatSpecifiers("k", c.value, c.devValue, "en.lproj")
+		if actual != c.expected {
+			t.Errorf("%v: %v\n", c.value, actual)
+		}
+	}
+}
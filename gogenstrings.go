@@ -1,79 +1,303 @@
 package main
 
 import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
 	"regexp"
+	"sort"
+	"strings"
 
 	"github.com/iawaknahc/gogenstrings/errors"
 )
 
-type genstringsContext struct {
-	// Configuration
-	rootPath      string
-	routineName   string
+const (
+	dotStringsExt       = ".strings"
+	infoPlistDotStrings = "InfoPlist.strings"
+)
+
+// Rule IDs of diagnostics.
+// Parse errors use the default rules of the errors package.
+const (
+	ruleDuplicatedKey       = "duplicated-key"
+	ruleEmptyKey            = "empty-key"
+	ruleInconsistentComment = "inconsistent-comment"
+	ruleInconsistentValue   = "inconsistent-value"
+	ruleOutdatedFile        = "outdated-file"
+	ruleFormatSpecifier     = "format-specifier"
+	rulePluralCategory      = "plural-category"
+	ruleMissingTranslation  = "missing-translation"
+	ruleMissingPlural       = "missing-plural"
+)
+
+// genstringsOptions is the configuration of genstringsContext.
+type genstringsOptions struct {
+	rootPath    string
+	routineName string
+	// devlang is derived by resolveDevlang if it is empty.
 	devlang       string
+	infoPlistPath string
 	excludeRegexp *regexp.Regexp
+	// swiftUI enables extraction of SwiftUI views
+	// and LocalizedStringKey.
+	swiftUI bool
+	// xcodeprojPath is the path to .xcodeproj.
+	// If it is set, the source files of xcodeprojTarget
+	// and the known regions are used instead of walking rootPath.
+	xcodeprojPath   string
+	xcodeprojTarget string
+	// interfaceBuilder enables extraction of
+	// storyboards and XIBs in Base.lproj.
+	interfaceBuilder bool
+	// settingsBundlePath is the path to Settings.bundle.
+	// Its plists are localized in its own lprojs.
+	settingsBundlePath string
+	// forceEncoding is the encoding of every written file.
+	// If it is empty, the encoding of the existing file is preserved.
+	forceEncoding encoding
+}
+
+type genstringsContext struct {
+	// Configuration
+	genstringsOptions
+
+	// Errors found in read and validate
+	diagnostics errors.Diagnostics
 
 	// Result of find
 	lprojs          []string
 	sourceFilePaths []string
 	devLproj        string
 
-	// Localizable.strings
-	// The key is lproj
-	inEntries   map[string]entries
-	inEntryMap  map[string]entryMap
-	outEntryMap map[string]entryMap
+	// The encoding of every read .strings file
+	// The key is the path
+	encodings map[string]encoding
+
+	// Localizable.strings and other tables
+	// The key is table name, then lproj
+	inEntries   map[string]map[string]entries
+	inEntryMap  map[string]map[string]entryMap
+	outEntryMap map[string]map[string]entryMap
+
+	// Localizable.stringsdict and other tables
+	// The key is table name, then lproj
+	// A table is processed only if the development language has it.
+	inStringsdicts  map[string]map[string]stringsdictEntryMap
+	outStringsdicts map[string]map[string]stringsdictEntryMap
+
+	// Localizable.xcstrings and other tables
+	// The key is table name
+	// A table having String Catalog has no .strings nor .stringsdict.
+	stringCatalogPaths map[string]string
+	inStringCatalogs   map[string]stringCatalog
+	outStringCatalogs  map[string]stringCatalog
+
+	// <Name>.strings of storyboards and XIBs
+	// The key is the path of the storyboard or XIB, then lproj
+	interfaceBuilderPaths       []string
+	interfaceBuilderLprojs      map[string][]string
+	interfaceBuilderEntries     map[string]entries
+	interfaceBuilderEntryMap    map[string]entryMap
+	inInterfaceBuilderEntries   map[string]map[string]entries
+	inInterfaceBuilderEntryMap  map[string]map[string]entryMap
+	outInterfaceBuilderEntryMap map[string]map[string]entryMap
+
+	// Root.strings and other tables of Settings.bundle
+	// The key is table name, then lproj
+	settingsPlistPaths  []string
+	settingsLprojs      []string
+	settingsDevLproj    string
+	settingsEntries     map[string]entries
+	settingsEntryMap    map[string]entryMap
+	inSettingsEntries   map[string]map[string]entries
+	inSettingsEntryMap  map[string]map[string]entryMap
+	outSettingsEntryMap map[string]map[string]entryMap
 
 	// Invocation of routine found in source code
-	// The key is translation key
+	// The key is table name, then translation key
 	routineCalls     routineCallSlice
-	routineCallByKey map[string]routineCall
+	routineCallByKey map[string]map[string]routineCall
+
+	// InfoPlist.strings
+	// The key is lproj
+	infoPlistEntries     entries
+	infoPlistEntryMap    entryMap
+	inInfoPlistEntries   map[string]entries
+	inInfoPlistEntryMap  map[string]entryMap
+	outInfoPlistEntryMap map[string]entryMap
 }
 
-func newGenstringsContext(rootPath, devlang, routineName string, exclude *regexp.Regexp) genstringsContext {
+func newGenstringsContext(options genstringsOptions) genstringsContext {
 	ctx := genstringsContext{
-		rootPath:      rootPath,
-		routineName:   routineName,
-		devlang:       devlang,
-		excludeRegexp: exclude,
+		genstringsOptions: options,
+
+		encodings: make(map[string]encoding),
+
+		inEntries:   make(map[string]map[string]entries),
+		inEntryMap:  make(map[string]map[string]entryMap),
+		outEntryMap: make(map[string]map[string]entryMap),
+
+		inStringsdicts:  make(map[string]map[string]stringsdictEntryMap),
+		outStringsdicts: make(map[string]map[string]stringsdictEntryMap),
+
+		stringCatalogPaths: make(map[string]string),
+		inStringCatalogs:   make(map[string]stringCatalog),
+		outStringCatalogs:  make(map[string]stringCatalog),
+
+		interfaceBuilderLprojs:      make(map[string][]string),
+		interfaceBuilderEntries:     make(map[string]entries),
+		interfaceBuilderEntryMap:    make(map[string]entryMap),
+		inInterfaceBuilderEntries:   make(map[string]map[string]entries),
+		inInterfaceBuilderEntryMap:  make(map[string]map[string]entryMap),
+		outInterfaceBuilderEntryMap: make(map[string]map[string]entryMap),
 
-		inEntries:   make(map[string]entries),
-		inEntryMap:  make(map[string]entryMap),
-		outEntryMap: make(map[string]entryMap),
+		settingsEntries:     make(map[string]entries),
+		settingsEntryMap:    make(map[string]entryMap),
+		inSettingsEntries:   make(map[string]map[string]entries),
+		inSettingsEntryMap:  make(map[string]map[string]entryMap),
+		outSettingsEntryMap: make(map[string]map[string]entryMap),
+
+		inInfoPlistEntries:   make(map[string]entries),
+		inInfoPlistEntryMap:  make(map[string]entryMap),
+		outInfoPlistEntryMap: make(map[string]entryMap),
 
 		routineCalls:     []routineCall{},
-		routineCallByKey: make(map[string]routineCall),
+		routineCallByKey: make(map[string]map[string]routineCall),
 	}
 	return ctx
 }
 
 func (p *genstringsContext) find() error {
-	if err := p.findLprojs(); err != nil {
+	if p.xcodeprojPath != "" {
+		if err := p.findXcodeproj(); err != nil {
+			return err
+		}
+		return p.findResources()
+	}
+	// Disagreement of the development language is always an error.
+	if err := p.resolveDevlang(); err != nil {
+		return err
+	}
+	if err := p.findStringCatalogs(); err != nil {
+		return err
+	}
+	if err := p.findAllLprojs(); err != nil {
 		return err
 	}
-	return p.findSourceFiles()
+	// The development lproj is required only by .strings.
+	// See read.
+	if !p.findDevLproj() && len(p.stringCatalogPaths) <= 0 {
+		return p.devLprojNotFoundErr()
+	}
+	if err := p.findSourceFiles(); err != nil {
+		return err
+	}
+	return p.findResources()
+}
+
+// findResources finds what is localized apart from source code.
+func (p *genstringsContext) findResources() error {
+	if err := p.findInterfaceBuilderFiles(); err != nil {
+		return err
+	}
+	return p.findSettingsBundle()
 }
 
 func (p *genstringsContext) findLprojs() error {
+	if err := p.resolveDevlang(); err != nil {
+		return err
+	}
+	if err := p.findAllLprojs(); err != nil {
+		return err
+	}
+	if !p.findDevLproj() {
+		return p.devLprojNotFoundErr()
+	}
+	return nil
+}
+
+func (p *genstringsContext) findAllLprojs() error {
 	lprojs, err := findLprojs(p.rootPath)
 	if err != nil {
 		return err
 	}
-	p.lprojs = lprojs
+	p.lprojs, err = p.withoutSettingsBundle(lprojs)
+	return err
+}
 
+// findDevLproj tells whether the lproj of
+// the development language is found.
+func (p *genstringsContext) findDevLproj() bool {
 	targetBasename := p.devlang + ".lproj"
-
 	for _, lproj := range p.lprojs {
 		basename := filepath.Base(lproj)
 		if basename == targetBasename {
 			p.devLproj = lproj
-			return nil
+			return true
+		}
+	}
+	return false
+}
+
+// withoutSettingsBundle removes the lprojs of Settings.bundle
+// which is localized on its own.
+// The root and Settings.bundle can be relative or absolute
+// so they are compared as absolute paths.
+func (p *genstringsContext) withoutSettingsBundle(lprojs []string) ([]string, error) {
+	if p.settingsBundlePath == "" {
+		return lprojs, nil
+	}
+	settingsBundlePath, err := filepath.Abs(p.settingsBundlePath)
+	if err != nil {
+		return nil, err
+	}
+	prefix := settingsBundlePath + string(filepath.Separator)
+	out := []string{}
+	for _, lproj := range lprojs {
+		abs, err := filepath.Abs(lproj)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(abs, prefix) {
+			out = append(out, lproj)
+		}
+	}
+	return out, nil
+}
+
+func (p *genstringsContext) devLprojNotFoundErr() error {
+	return errors.File(path.Join(p.rootPath, p.devlang+".lproj"), "directory not found")
+}
+
+func (p *genstringsContext) findStringCatalogs() error {
+	catalogPaths, err := findStringCatalogs(p.rootPath, p.excludeRegexp)
+	if err != nil {
+		return err
+	}
+	for _, catalogPath := range catalogPaths {
+		table := strings.TrimSuffix(filepath.Base(catalogPath), dotXCStringsExt)
+		if existing, ok := p.stringCatalogPaths[table]; ok {
+			return errors.File(catalogPath, fmt.Sprintf("duplicated String Catalog `%v`", existing))
+		}
+		p.stringCatalogPaths[table] = catalogPath
+	}
+	return nil
+}
+
+// needsDevLproj tells whether any file in lproj is read.
+func (p *genstringsContext) needsDevLproj() bool {
+	if p.infoPlistPath != "" {
+		return true
+	}
+	for _, table := range p.routineCalls.tables() {
+		if _, ok := p.stringCatalogPaths[table]; !ok {
+			return true
 		}
 	}
-	return errors.File(path.Join(p.rootPath, targetBasename), "directory not found")
+	return false
 }
 
 func (p *genstringsContext) findSourceFiles() error {
@@ -85,115 +309,565 @@ func (p *genstringsContext) findSourceFiles() error {
 	return nil
 }
 
-func (p *genstringsContext) read() error {
-	if err := p.readLocalizableDotStrings(); err != nil {
-		return err
+// read reads every file.
+// Errors are collected in diagnostics so that
+// all of them can be reported at once.
+func (p *genstringsContext) read() {
+	// Routine calls tell which tables are in use.
+	p.readRoutineCalls()
+	p.readStringCatalogs(p.routineCalls.tables())
+	// Storyboards, XIBs and Settings.bundle have their own lprojs.
+	p.readInterfaceBuilderFiles()
+	p.readSettingsBundle()
+	if p.devLproj == "" {
+		if p.needsDevLproj() {
+			p.diagnostics.Add(p.devLprojNotFoundErr())
+		}
+		return
 	}
-	return p.readRoutineCalls()
+	p.readTableDotStrings()
+	p.readStringsdicts()
+	if p.infoPlistPath == "" {
+		return
+	}
+	p.readInfoPlistDotStrings()
+	p.readInfoPlist()
 }
 
-func (p *genstringsContext) readLocalizableDotStrings() error {
-	for _, lproj := range p.lprojs {
-		fullpath := lproj + "/Localizable.strings"
-		content, err := readFile(fullpath)
-		if err != nil {
-			if !os.IsNotExist(err) {
-				return err
+func (p *genstringsContext) readTableDotStrings() {
+	for _, table := range p.routineCalls.tables() {
+		if _, ok := p.stringCatalogPaths[table]; ok {
+			continue
+		}
+		in := make(map[string]entries)
+		p.readDotStrings(table+dotStringsExt, in)
+		p.inEntries[table] = in
+	}
+}
+
+func (p *genstringsContext) readStringsdicts() {
+	for _, table := range p.routineCalls.tables() {
+		if _, ok := p.stringCatalogPaths[table]; ok {
+			continue
+		}
+		in := make(map[string]stringsdictEntryMap)
+		for _, lproj := range p.lprojs {
+			fullpath := lproj + "/" + table + dotStringsdictExt
+			content, err := readFile(fullpath)
+			if err != nil {
+				if !os.IsNotExist(err) {
+					p.diagnostics.Add(err)
+				}
+				continue
 			}
-			p.inEntries[lproj] = entries{}
-		} else {
-			es, err := parseDotStrings(content, fullpath)
+			em, err := parseStringsdict(content, fullpath)
 			if err != nil {
-				return err
+				p.diagnostics.Add(err)
+				continue
 			}
-			p.inEntries[lproj] = es
+			in[lproj] = em
 		}
+		p.inStringsdicts[table] = in
 	}
-	return nil
 }
 
-func (p *genstringsContext) validate() error {
-	if err := p.validateLocalizableDotStrings(); err != nil {
-		return err
+func (p *genstringsContext) readStringCatalogs(tables []string) {
+	for _, table := range tables {
+		fullpath, ok := p.stringCatalogPaths[table]
+		if !ok {
+			continue
+		}
+		content, err := readFile(fullpath)
+		if err != nil {
+			p.diagnostics.Add(err)
+			continue
+		}
+		c, err := parseStringCatalog(content, fullpath)
+		if err != nil {
+			p.diagnostics.Add(err)
+			continue
+		}
+		p.inStringCatalogs[table] = c
 	}
-	return p.validateRoutineCalls()
 }
 
-func (p *genstringsContext) validateLocalizableDotStrings() error {
-	for lproj, es := range p.inEntries {
-		em, err := es.toEntryMap()
+func (p *genstringsContext) readInfoPlistDotStrings() {
+	p.readDotStrings(infoPlistDotStrings, p.inInfoPlistEntries)
+}
+
+func (p *genstringsContext) readDotStrings(basename string, out map[string]entries) {
+	p.readDotStringsIn(p.lprojs, basename, out)
+}
+
+func (p *genstringsContext) readDotStringsIn(lprojs []string, basename string, out map[string]entries) {
+	for _, lproj := range lprojs {
+		fullpath := lproj + "/" + basename
+		content, enc, err := readFileEncoding(fullpath)
 		if err != nil {
-			return err
+			if !os.IsNotExist(err) {
+				p.diagnostics.Add(err)
+				continue
+			}
+			out[lproj] = entries{}
+		} else {
+			p.encodings[fullpath] = enc
+			es, err := parseDotStrings(content, fullpath)
+			if err != nil {
+				p.diagnostics.Add(err)
+				continue
+			}
+			out[lproj] = es
 		}
-		p.inEntryMap[lproj] = em
 	}
-	return nil
 }
 
-func (p *genstringsContext) validateRoutineCalls() error {
-	out, err := p.routineCalls.toMap()
+func (p *genstringsContext) readInfoPlist() {
+	content, err := readFile(p.infoPlistPath)
 	if err != nil {
-		return err
+		p.diagnostics.Add(err)
+		return
 	}
-	p.routineCallByKey = out
-	return nil
+	es, err := parseInfoPlist(content, p.infoPlistPath)
+	if err != nil {
+		p.diagnostics.Add(err)
+		return
+	}
+	p.infoPlistEntries = es
 }
 
-func (p *genstringsContext) readRoutineCalls() error {
+func (p *genstringsContext) readRoutineCalls() {
 	for _, fullpath := range p.sourceFilePaths {
 		content, err := readFile(fullpath)
 		if err != nil {
-			return err
+			p.diagnostics.Add(err)
+			continue
 		}
-		calls, err := parseRoutineCalls(content, p.routineName, fullpath)
+		calls, err := parseRoutineCalls(content, p.routineName, fullpath, p.swiftUI)
 		if err != nil {
-			return err
+			p.diagnostics.Add(err)
+			continue
 		}
 		for _, call := range calls {
 			p.routineCalls = append(p.routineCalls, call)
 		}
 	}
-	return nil
+}
+
+// validate validates what have been read.
+// Like read, errors are collected in diagnostics.
+func (p *genstringsContext) validate() {
+	p.validateTableDotStrings()
+	p.validateRoutineCalls()
+	p.validateInterfaceBuilderFiles()
+	p.validateSettingsBundle()
+	if p.infoPlistPath == "" {
+		return
+	}
+	p.validateInfoPlistDotStrings()
+	p.validateInfoPlist()
+}
+
+func (p *genstringsContext) validateTableDotStrings() {
+	for _, table := range sortedTables(p.inEntries) {
+		out := make(map[string]entryMap)
+		p.validateDotStrings(p.inEntries[table], out)
+		p.inEntryMap[table] = out
+	}
+}
+
+func sortedTables(m map[string]map[string]entries) []string {
+	out := []string{}
+	for table := range m {
+		out = append(out, table)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (p *genstringsContext) validateInfoPlistDotStrings() {
+	p.validateDotStrings(p.inInfoPlistEntries, p.inInfoPlistEntryMap)
+}
+
+func (p *genstringsContext) validateDotStrings(in map[string]entries, out map[string]entryMap) {
+	for lproj, es := range in {
+		em, err := es.toEntryMap()
+		p.diagnostics.Add(err)
+		out[lproj] = em
+	}
+}
+
+func (p *genstringsContext) validateInfoPlist() {
+	em, err := p.infoPlistEntries.toEntryMap()
+	p.diagnostics.Add(err)
+	p.infoPlistEntryMap = em
+}
+
+func (p *genstringsContext) validateRoutineCalls() {
+	// Keys only have to be unique within a table.
+	callsByTable := p.routineCalls.groupByTable()
+	for _, table := range p.routineCalls.tables() {
+		out, err := callsByTable[table].toMap()
+		p.diagnostics.Add(err)
+		p.routineCallByKey[table] = out
+	}
 }
 
 func (p *genstringsContext) process() {
 	devLproj := p.devLproj
-	// Merge development language first
-	oldDevEntryMap := p.inEntryMap[devLproj]
-	p.outEntryMap[devLproj] = oldDevEntryMap.mergeCalls(p.routineCallByKey)
+	for table, inEntryMap := range p.inEntryMap {
+		outEntryMap := make(map[string]entryMap)
+
+		// Merge development language first
+		oldDevEntryMap := inEntryMap[devLproj]
+		outEntryMap[devLproj] = oldDevEntryMap.mergeCalls(p.routineCallByKey[table])
+
+		// Merge other languages
+		for lproj, em := range inEntryMap {
+			if lproj == devLproj {
+				continue
+			}
+			outEntryMap[lproj] = em.mergeDev(outEntryMap[devLproj])
+		}
+
+		p.outEntryMap[table] = outEntryMap
+	}
+
+	// Only keys of the development language are plural.
+	for table, in := range p.inStringsdicts {
+		devEntryMap, ok := in[devLproj]
+		if !ok {
+			continue
+		}
+		out := make(map[string]stringsdictEntryMap)
+		out[devLproj] = devEntryMap.mergeCalls(p.routineCallByKey[table])
+		for _, lproj := range p.lprojs {
+			if lproj == devLproj {
+				continue
+			}
+			em := in[lproj].mergeDev(out[devLproj], pluralCategoriesOfLproj(lproj))
+			// Do not create an empty file
+			if _, ok := in[lproj]; !ok && len(em) <= 0 {
+				continue
+			}
+			out[lproj] = em
+		}
+		p.outStringsdicts[table] = out
+	}
+
+	// Stale keys are kept in String Catalogs.
+	for table, c := range p.inStringCatalogs {
+		p.outStringCatalogs[table] = c.mergeCalls(p.routineCallByKey[table])
+	}
 
-	// Merge other languages
-	for lproj, em := range p.inEntryMap {
+	p.processInterfaceBuilderFiles()
+	p.processSettingsBundle()
+
+	if p.infoPlistPath == "" {
+		return
+	}
+
+	// Merge InfoPlist.strings in the same way
+	oldDevInfoPlistEntryMap := p.inInfoPlistEntryMap[devLproj]
+	p.outInfoPlistEntryMap[devLproj] = oldDevInfoPlistEntryMap.mergeInfoPlist(p.infoPlistEntryMap)
+	for lproj, em := range p.inInfoPlistEntryMap {
 		if lproj == devLproj {
 			continue
 		}
-		p.outEntryMap[lproj] = em.mergeDev(p.outEntryMap[devLproj])
+		p.outInfoPlistEntryMap[lproj] = em.mergeDev(p.outInfoPlistEntryMap[devLproj])
 	}
 }
 
-func (p *genstringsContext) write() error {
-	// Write Localizable.strings
-	for lproj, em := range p.outEntryMap {
+// validateFormatSpecifiers reports every translation
+// whose format specifiers differ from the development language.
+// Like validate, errors are collected in diagnostics.
+func (p *genstringsContext) validateFormatSpecifiers() {
+	devLproj := p.devLproj
+	devLprojName := filepath.Base(devLproj)
+	for table, outEntryMap := range p.outEntryMap {
+		basename := table + dotStringsExt
+		devEntryMap := outEntryMap[devLproj]
+		for key, devEntry := range devEntryMap {
+			if _, err := parseFormatSpecifiers(devEntry.value); err != nil {
+				p.diagnostics.Add(formatSpecifierErr(
+					devEntry,
+					devLproj+"/"+basename,
+					fmt.Sprintf("`%v` %v", key, err),
+				))
+			}
+		}
+		for lproj, em := range outEntryMap {
+			if lproj == devLproj {
+				continue
+			}
+			for key, e := range em {
+				devEntry, ok := devEntryMap[key]
+				if !ok {
+					continue
+				}
+				msg := compareFormatSpecifiers(key, e.value, devEntry.value, devLprojName)
+				if msg != "" {
+					p.diagnostics.Add(formatSpecifierErr(e, lproj+"/"+basename, msg))
+				}
+			}
+		}
+	}
+}
+
+// validatePluralCategories reports every plural rule
+// which misses the categories of its language.
+// Like validate, errors are collected in diagnostics.
+func (p *genstringsContext) validatePluralCategories() {
+	for table, out := range p.outStringsdicts {
+		for lproj, em := range out {
+			categories := pluralCategoriesOfLproj(lproj)
+			language := filepath.Base(lproj)
+			targetPath := lproj + "/" + table + dotStringsdictExt
+			for _, entry := range em {
+				p.diagnostics.Add(entry.validatePluralCategories(categories, language, targetPath))
+			}
+		}
+	}
+}
+
+// validatePluralCalls reports every key formatted by
+// localizedStringWithFormat with an integer
+// which is missing in the .stringsdict of the development language.
+// Tables without .stringsdict do not use plurals.
+func (p *genstringsContext) validatePluralCalls() {
+	devLproj := p.devLproj
+	for table, in := range p.inStringsdicts {
+		devEntryMap, ok := in[devLproj]
+		if !ok {
+			continue
+		}
+		targetPath := devLproj + "/" + table + dotStringsdictExt
+		for key, call := range p.routineCallByKey[table] {
+			if !call.withFormat {
+				continue
+			}
+			if _, ok := devEntryMap[key]; ok {
+				continue
+			}
+			format := key
+			if devEntry, ok := p.outEntryMap[table][devLproj][key]; ok {
+				format = devEntry.value
+			}
+			if !hasIntegerFormatSpecifier(format) {
+				continue
+			}
+			p.diagnostics.Add(errors.FileLineCol(
+				call.filepath,
+				call.startLine,
+				call.startCol,
+				fmt.Sprintf("`%v` is formatted with an integer but is missing in %v", key, targetPath),
+			).WithEnd(call.endLine, call.endCol).WithRule(ruleMissingPlural))
+		}
+	}
+}
+
+// formatSpecifierErr points at e if it is read from a file.
+// Otherwise it points at targetPath.
+func formatSpecifierErr(e entry, targetPath, msg string) error {
+	if e.filepath == "" {
+		return errors.File(targetPath, msg).WithRule(ruleFormatSpecifier)
+	}
+	return positionErr(ruleFormatSpecifier, e.filepath, e.startLine, e.startCol, msg)
+}
+
+// fileLineColErr is errors.FileLineCol without rule.
+// It is errors.File if line is unknown, e.g. in binary plist.
+func fileLineColErr(filepath string, line, col int, msg string) error {
+	if line <= 0 {
+		return errors.File(filepath, msg)
+	}
+	return errors.FileLineCol(filepath, line, col, msg)
+}
+
+// positionErr is like fileLineColErr but with rule.
+func positionErr(rule, filepath string, line, col int, msg string) error {
+	if line <= 0 {
+		return errors.File(filepath, msg).WithRule(rule)
+	}
+	return errors.FileLineCol(filepath, line, col, msg).WithRule(rule)
+}
+
+// render returns the content of every output file.
+// The key is the target path.
+func (p *genstringsContext) render() map[string]string {
+	out := make(map[string]string)
+	// Render Localizable.strings and other tables
+	for table, outEntryMap := range p.outEntryMap {
+		p.renderDotStrings(out, table+dotStringsExt, outEntryMap, false)
+	}
+	// Render Localizable.stringsdict and other tables
+	for table, outStringsdict := range p.outStringsdicts {
+		for lproj, em := range outStringsdict {
+			out[lproj+"/"+table+dotStringsdictExt] = em.print()
+		}
+	}
+	// Render Localizable.xcstrings and other tables
+	for table, c := range p.outStringCatalogs {
+		out[p.stringCatalogPaths[table]] = c.print()
+	}
+	// Render <Name>.strings of storyboards and XIBs
+	for ibPath, outEntryMap := range p.outInterfaceBuilderEntryMap {
+		p.renderDotStrings(out, interfaceBuilderTable(ibPath)+dotStringsExt, outEntryMap, false)
+	}
+	// Render Root.strings and other tables of Settings.bundle
+	for table, outEntryMap := range p.outSettingsEntryMap {
+		p.renderDotStrings(out, table+dotStringsExt, outEntryMap, true)
+	}
+	// Render InfoPlist.strings
+	// Keys in Info.plist do not have comment.
+	p.renderDotStrings(out, infoPlistDotStrings, p.outInfoPlistEntryMap, true)
+	return out
+}
+
+func (p *genstringsContext) renderDotStrings(out map[string]string, basename string, outEntryMap map[string]entryMap, suppressEmptyComment bool) {
+	for lproj, em := range outEntryMap {
 		sorted := em.toEntries().sort()
-		content := sorted.print(false)
-		targetPath := lproj + "/Localizable.strings"
-		if err := writeFile(targetPath, content); err != nil {
+		content := sorted.print(suppressEmptyComment)
+		targetPath := lproj + "/" + basename
+		out[targetPath] = content
+	}
+}
+
+// targetEncoding returns the encoding to write targetPath in.
+// New files are written in UTF-8.
+func (p *genstringsContext) targetEncoding(targetPath string) encoding {
+	// XML plist is always in UTF-8.
+	if path.Ext(targetPath) != dotStringsExt {
+		return encodingUTF8
+	}
+	if p.forceEncoding != "" {
+		return p.forceEncoding
+	}
+	if enc, ok := p.encodings[targetPath]; ok {
+		return enc
+	}
+	return encodingUTF8
+}
+
+func (p *genstringsContext) write() error {
+	for targetPath, content := range p.render() {
+		if err := writeFile(targetPath, content, p.targetEncoding(targetPath)); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (p *genstringsContext) genstrings() error {
+// outdatedFiles returns the sorted target paths
+// whose content on disk differs from the rendered output.
+// A file in another encoding is also outdated.
+func (p *genstringsContext) outdatedFiles() ([]string, error) {
+	out := []string{}
+	for targetPath, content := range p.render() {
+		existing, err := ioutil.ReadFile(targetPath)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				return nil, err
+			}
+			out = append(out, targetPath)
+		} else if !bytes.Equal(existing, encode(content, p.targetEncoding(targetPath))) {
+			out = append(out, targetPath)
+		}
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// diff returns the unified diff of every outdated file.
+// A change of encoding is described before the diff.
+func (p *genstringsContext) diff() (string, error) {
+	rendered := p.render()
+	targetPaths := []string{}
+	for targetPath := range rendered {
+		targetPaths = append(targetPaths, targetPath)
+	}
+	sort.Strings(targetPaths)
+
+	buf := bytes.Buffer{}
+	for _, targetPath := range targetPaths {
+		fromPath := targetPath
+		existing, enc, err := readFileEncoding(targetPath)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				return "", err
+			}
+			fromPath = "/dev/null"
+		} else if targetEnc := p.targetEncoding(targetPath); enc != targetEnc {
+			buf.WriteString(encodingDiff(targetPath, enc, targetEnc))
+		}
+		buf.WriteString(unifiedDiff(fromPath, targetPath, existing, rendered[targetPath]))
+	}
+	return buf.String(), nil
+}
+
+// load runs everything before process.
+func (p *genstringsContext) load() error {
 	if err := p.find(); err != nil {
 		return err
 	}
-	if err := p.read(); err != nil {
+	p.read()
+	p.validate()
+	return p.diagnostics.Err()
+}
+
+// validateOutput validates the result of process.
+func (p *genstringsContext) validateOutput() error {
+	p.validateFormatSpecifiers()
+	p.validatePluralCategories()
+	p.validatePluralCalls()
+	return p.diagnostics.Err()
+}
+
+// run loads and processes.
+// The diagnostics of validateOutput are returned as invalid
+// because a bad translation does not stop writing the other files.
+func (p *genstringsContext) run() (invalid error, err error) {
+	if err := p.load(); err != nil {
+		return nil, err
+	}
+	p.process()
+	return p.validateOutput(), nil
+}
+
+// dryRun is like genstrings but writes nothing.
+// It returns the unified diff of the changes.
+func (p *genstringsContext) dryRun() (string, error) {
+	invalid, err := p.run()
+	if err != nil {
+		return "", err
+	}
+	diff, err := p.diff()
+	if err != nil {
+		return "", err
+	}
+	return diff, invalid
+}
+
+func (p *genstringsContext) genstrings() error {
+	invalid, err := p.run()
+	if err != nil {
 		return err
 	}
-	if err := p.validate(); err != nil {
+	if err := p.write(); err != nil {
 		return err
 	}
-	p.process()
-	return p.write()
+	return invalid
+}
+
+// check is like genstrings but writes nothing.
+// It returns the files that would be changed.
+// Unlike genstrings, a bad translation fails the check.
+func (p *genstringsContext) check() ([]string, error) {
+	invalid, err := p.run()
+	if err != nil {
+		return nil, err
+	}
+	if invalid != nil {
+		return nil, invalid
+	}
+	return p.outdatedFiles()
 }
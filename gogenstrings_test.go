@@ -1,17 +1,398 @@
 package main
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/iawaknahc/gogenstrings/errors"
 )
 
 func TestFoo(t *testing.T) {
-	ctx := newGenstringsContext(
-		"./example",
-		"en",
-		"NSLocalizedString",
-		nil,
-	)
+	ctx := newGenstringsContext(genstringsOptions{
+		rootPath:      "./example",
+		devlang:       "en",
+		routineName:   "NSLocalizedString",
+		infoPlistPath: "./example/Info.plist",
+	})
+	if err := ctx.genstrings(); err != nil {
+		t.Errorf("%v\n", err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	ctx := newGenstringsContext(genstringsOptions{
+		rootPath:      "./example",
+		devlang:       "en",
+		routineName:   "NSLocalizedString",
+		infoPlistPath: "./example/Info.plist",
+	})
+	if err := ctx.genstrings(); err != nil {
+		t.Errorf("%v\n", err)
+	}
+
+	ctx = newGenstringsContext(genstringsOptions{
+		rootPath:      "./example",
+		devlang:       "en",
+		routineName:   "NSLocalizedString",
+		infoPlistPath: "./example/Info.plist",
+	})
+	outdated, err := ctx.check()
+	if err != nil {
+		t.Errorf("%v\n", err)
+	} else if len(outdated) != 0 {
+		t.Errorf("%v\n", outdated)
+	}
+}
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	for name, content := range files {
+		fullpath := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(fullpath), 0755); err != nil {
+			t.Fatalf("%v\n", err)
+		}
+		if err := ioutil.WriteFile(fullpath, []byte(content), 0644); err != nil {
+			t.Fatalf("%v\n", err)
+		}
+	}
+}
+
+func TestCollectErrors(t *testing.T) {
+	root, err := ioutil.TempDir("", "gogenstrings")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	defer os.RemoveAll(root)
+
+	files := map[string]string{
+		"en.lproj/Localizable.strings": "a = a;\nb = b;\na = c;\nb = d;\n",
+		"ja.lproj/Localizable.strings": "a = ;",
+		"A.swift":                      `NSLocalizedString("a", comment: "1")` + "\n" + `NSLocalizedString("a", comment: "2")`,
+		"B.swift":                      `NSLocalizedString("a" comment: "1")`,
+		"C.m":                          `NSLocalizedString(@"c", @"`,
+	}
+	writeFiles(t, root, files)
+
+	ctx := newGenstringsContext(genstringsOptions{
+		rootPath:    root,
+		devlang:     "en",
+		routineName: "NSLocalizedString",
+	})
+	err = ctx.genstrings()
+	if err == nil {
+		t.Fatalf("expected error\n")
+	}
+	expected := []string{
+		root + "/A.swift:2:1: routine call `a` has different comment",
+		root + "/B.swift:1:23: unexpected token `<ident>`",
+		root + "/C.m:1:26: unterminated string literal",
+		root + "/en.lproj/Localizable.strings:3:1: duplicated key `a`",
+		root + "/en.lproj/Localizable.strings:4:1: duplicated key `b`",
+		root + "/ja.lproj/Localizable.strings:1:5: unexpected token `;`",
+	}
+	if actual := err.Error(); actual != strings.Join(expected, "\n") {
+		t.Errorf("%v\n", actual)
+	}
+}
+
+func TestCollectErrorsDiagnostics(t *testing.T) {
+	err := errors.List{
+		errors.FileLineCol("A.swift", 2, 1, "a").WithRule(ruleInconsistentComment),
+		lexItem{Type: itemIdentifier, Filepath: "B.swift", StartLine: 1, StartCol: 23, EndLine: 1, EndCol: 30}.unexpectedTokenErr(),
+	}
+	diagnostics := errors.ToDiagnostics(err)
+	expected := []errors.Diagnostic{
+		{RuleID: "inconsistent-comment", Severity: "error", Filepath: "A.swift", Line: 2, Col: 1, Message: "a"},
+		{RuleID: "syntax", Severity: "error", Filepath: "B.swift", Line: 1, Col: 23, EndLine: 1, EndCol: 30, Message: "unexpected token `<ident>`"},
+	}
+	if !reflect.DeepEqual(diagnostics, expected) {
+		t.Errorf("%v\n", diagnostics)
+	}
+}
+
+func TestValidateFormatSpecifiers(t *testing.T) {
+	root, err := ioutil.TempDir("", "gogenstrings")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	defer os.RemoveAll(root)
+
+	writeFiles(t, root, map[string]string{
+		"en.lproj/Localizable.strings": "a = \"%@ liked your post\";\nb = \"%d items\";\nc = \"%1$@ %@\";\n",
+		"ja.lproj/Localizable.strings": "a = \"いいね\";\nb = \"%@ 個\";\nc = \"c\";\n",
+		"fr.lproj/Localizable.strings": "a = \"%@ a aimé\";\nb = \"%1$d éléments\";\n",
+		"A.swift": `NSLocalizedString("a", comment: "")` + "\n" +
+			`NSLocalizedString("b", comment: "")` + "\n" +
+			`NSLocalizedString("c", comment: "")`,
+	})
+
+	ctx := newGenstringsContext(genstringsOptions{
+		rootPath:    root,
+		devlang:     "en",
+		routineName: "NSLocalizedString",
+	})
+	err = ctx.genstrings()
+	if err == nil {
+		t.Fatalf("expected error\n")
+	}
+	expected := []string{
+		root + "/en.lproj/Localizable.strings:3:1: `c` mixes positional and non-positional format specifiers",
+		root + "/ja.lproj/Localizable.strings:1:1: `a` has 0 format argument(s) but en.lproj has 1",
+		root + "/ja.lproj/Localizable.strings:2:1: `b` uses argument 1 as `%@` but en.lproj uses it as `%d`",
+	}
+	if actual := err.Error(); actual != strings.Join(expected, "\n") {
+		t.Errorf("%v\n", actual)
+	}
+}
+
+func TestValidateFormatSpecifiersDoNotBlockWrite(t *testing.T) {
+	root, err := ioutil.TempDir("", "gogenstrings")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	defer os.RemoveAll(root)
+
+	writeFiles(t, root, map[string]string{
+		"en.lproj/Localizable.strings": "a = \"%d items\";\n",
+		"ja.lproj/Localizable.strings": "a = \"%@ 個\";\n",
+		"A.swift": `NSLocalizedString("a", comment: "")` + "\n" +
+			`NSLocalizedString("b", comment: "")` + "\n" +
+			`NSLocalizedString("c", tableName: "Other", comment: "")`,
+	})
+
+	ctx := newGenstringsContext(genstringsOptions{
+		rootPath:    root,
+		devlang:     "en",
+		routineName: "NSLocalizedString",
+	})
+	err = ctx.genstrings()
+	if err == nil || err.Error() != root+"/ja.lproj/Localizable.strings:1:1: `a` uses argument 1 as `%@` but en.lproj uses it as `%d`" {
+		t.Errorf("%v\n", err)
+	}
+	// Unaffected keys and tables are still written.
+	expected := map[string]string{
+		"en.lproj/Localizable.strings": "/* No comment provided by engineer. */\n\"a\" = \"%d items\";\n\n/* No comment provided by engineer. */\n\"b\" = \"b\";\n\n",
+		"ja.lproj/Localizable.strings": "/* No comment provided by engineer. */\n\"a\" = \"%@ 個\";\n\n/* No comment provided by engineer. */\n\"b\" = \"b\";\n\n",
+		"en.lproj/Other.strings":       "/* No comment provided by engineer. */\n\"c\" = \"c\";\n\n",
+	}
+	for name, content := range expected {
+		if actual, err := readFile(filepath.Join(root, name)); err != nil || actual != content {
+			t.Errorf("%v: %q %v\n", name, actual, err)
+		}
+	}
+
+	// -check still fails.
+	ctx = newGenstringsContext(genstringsOptions{
+		rootPath:    root,
+		devlang:     "en",
+		routineName: "NSLocalizedString",
+	})
+	if _, err := ctx.check(); err == nil {
+		t.Errorf("expected error\n")
+	}
+}
+
+func TestPreserveEncoding(t *testing.T) {
+	root, err := ioutil.TempDir("", "gogenstrings")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	defer os.RemoveAll(root)
+
+	writeFiles(t, root, map[string]string{
+		"en.lproj/Localizable.strings": "",
+		"ja.lproj/Localizable.strings": string(encode("\"a\" = \"エー\";\n", encodingUTF16LE)),
+		"A.swift":                      `NSLocalizedString("a", comment: "")`,
+	})
+	enPath := filepath.Join(root, "en.lproj/Localizable.strings")
+	jaPath := filepath.Join(root, "ja.lproj/Localizable.strings")
+
+	newContext := func(forceEncoding encoding) genstringsContext {
+		return newGenstringsContext(genstringsOptions{
+			rootPath:      root,
+			devlang:       "en",
+			routineName:   "NSLocalizedString",
+			forceEncoding: forceEncoding,
+		})
+	}
+
+	ctx := newContext("")
+	if err := ctx.genstrings(); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	expected := "/* No comment provided by engineer. */\n\"a\" = \"エー\";\n\n"
+	if actual, enc, err := readFileEncoding(jaPath); err != nil || actual != expected || enc != encodingUTF16LE {
+		t.Errorf("%q %v %v\n", actual, enc, err)
+	}
+	if _, enc, err := readFileEncoding(enPath); err != nil || enc != encodingUTF8 {
+		t.Errorf("%v %v\n", enc, err)
+	}
+
+	// Forcing another encoding makes every file outdated.
+	ctx = newContext(encodingUTF16BE)
+	outdated, err := ctx.check()
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if !reflect.DeepEqual(outdated, []string{enPath, jaPath}) {
+		t.Errorf("%v\n", outdated)
+	}
+	ctx = newContext(encodingUTF16BE)
 	if err := ctx.genstrings(); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if actual, enc, err := readFileEncoding(jaPath); err != nil || actual != expected || enc != encodingUTF16BE {
+		t.Errorf("%q %v %v\n", actual, enc, err)
+	}
+}
+
+func TestPreserveUTF8BOM(t *testing.T) {
+	root, err := ioutil.TempDir("", "gogenstrings")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	defer os.RemoveAll(root)
+
+	writeFiles(t, root, map[string]string{
+		"en.lproj/Localizable.strings": "\xEF\xBB\xBF",
+		"A.swift":                      `NSLocalizedString("a", comment: "")`,
+	})
+	enPath := filepath.Join(root, "en.lproj/Localizable.strings")
+
+	ctx := newGenstringsContext(genstringsOptions{
+		rootPath:    root,
+		devlang:     "en",
+		routineName: "NSLocalizedString",
+	})
+	if err := ctx.genstrings(); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	expected := "/* No comment provided by engineer. */\n\"a\" = \"a\";\n\n"
+	if actual, enc, err := readFileEncoding(enPath); err != nil || actual != expected || enc != encodingUTF8BOM {
+		t.Errorf("%q %v %v\n", actual, enc, err)
+	}
+
+	// A change of encoding alone is shown in the diff.
+	ctx = newGenstringsContext(genstringsOptions{
+		rootPath:      root,
+		devlang:       "en",
+		routineName:   "NSLocalizedString",
+		forceEncoding: encodingUTF8,
+	})
+	diff, err := ctx.dryRun()
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if expected := "encoding of " + enPath + " changes from utf-8-bom to utf-8\n"; diff != expected {
+		t.Errorf("%q\n", diff)
+	}
+}
+
+func TestStringsdict(t *testing.T) {
+	root, err := ioutil.TempDir("", "gogenstrings")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	defer os.RemoveAll(root)
+
+	writeFiles(t, root, map[string]string{
+		"en.lproj/Localizable.stringsdict": devStringsdict,
+		"ja.lproj/Localizable.stringsdict": strings.Replace(devStringsdict, "n_items", "unused", 1),
+		"pl.lproj/Localizable.strings":     "",
+		"A.swift":                          `String.localizedStringWithFormat(NSLocalizedString("n_items", comment: ""), n)`,
+	})
+
+	ctx := newGenstringsContext(genstringsOptions{
+		rootPath:    root,
+		devlang:     "en",
+		routineName: "NSLocalizedString",
+	})
+	if err := ctx.genstrings(); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	// Unused key is removed and missing key gets a skeleton.
+	expected := map[string]string{
+		"en.lproj": devStringsdict,
+		"ja.lproj": strings.Replace(devStringsdict, "\t\t\t<key>one</key>\n\t\t\t<string>%d item</string>\n", "", 1),
+		"pl.lproj": strings.Replace(
+			devStringsdict,
+			"\t\t\t<key>other</key>\n",
+			"\t\t\t<key>few</key>\n\t\t\t<string>%d items</string>\n\t\t\t<key>many</key>\n\t\t\t<string>%d items</string>\n\t\t\t<key>other</key>\n",
+			1,
+		),
+	}
+	for lproj, content := range expected {
+		actual, err := readFile(filepath.Join(root, lproj, "Localizable.stringsdict"))
+		if err != nil {
+			t.Errorf("%v\n", err)
+		} else if actual != content {
+			t.Errorf("%v: %v\n", lproj, actual)
+		}
+	}
+
+	// Missing categories are reported.
+	writeFiles(t, root, map[string]string{
+		"pl.lproj/Localizable.stringsdict": devStringsdict,
+	})
+	ctx = newGenstringsContext(genstringsOptions{
+		rootPath:    root,
+		devlang:     "en",
+		routineName: "NSLocalizedString",
+	})
+	err = ctx.genstrings()
+	expectedErr := []string{
+		root + "/pl.lproj/Localizable.stringsdict:9:3: `n_items` is missing plural category `few` of `items` for pl.lproj",
+		root + "/pl.lproj/Localizable.stringsdict:9:3: `n_items` is missing plural category `many` of `items` for pl.lproj",
+	}
+	if err == nil || err.Error() != strings.Join(expectedErr, "\n") {
+		t.Errorf("%v\n", err)
+	}
+}
+
+func TestStringsdictFormatInVariable(t *testing.T) {
+	root, err := ioutil.TempDir("", "gogenstrings")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	defer os.RemoveAll(root)
+
+	writeFiles(t, root, map[string]string{
+		"en.lproj/Localizable.stringsdict": devStringsdict,
+		"ja.lproj/Localizable.stringsdict": devStringsdict,
+		"A.swift":                          "let f = NSLocalizedString(\"n_items\", comment: \"\")\nString.localizedStringWithFormat(f, n)\n",
+	})
+
+	ctx := newGenstringsContext(genstringsOptions{
+		rootPath:    root,
+		devlang:     "en",
+		routineName: "NSLocalizedString",
+	})
+	if err := ctx.genstrings(); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	for _, lproj := range []string{"en.lproj", "ja.lproj"} {
+		if actual, err := readFile(filepath.Join(root, lproj, "Localizable.stringsdict")); err != nil || actual != devStringsdict {
+			t.Errorf("%v: %v %v\n", lproj, actual, err)
+		}
+	}
+
+	// A plural format missing in .stringsdict is reported.
+	writeFiles(t, root, map[string]string{
+		"B.swift": "String.localizedStringWithFormat(NSLocalizedString(\"%d files\", comment: \"\"), n)\n" +
+			"String.localizedStringWithFormat(NSLocalizedString(\"Hello %@\", comment: \"\"), name)\n",
+	})
+	ctx = newGenstringsContext(genstringsOptions{
+		rootPath:    root,
+		devlang:     "en",
+		routineName: "NSLocalizedString",
+	})
+	err = ctx.genstrings()
+	expected := root + "/B.swift:1:34: `%d files` is formatted with an integer but is missing in " + root + "/en.lproj/Localizable.stringsdict"
+	if err == nil || err.Error() != expected {
 		t.Errorf("%v\n", err)
 	}
 }
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/iawaknahc/gogenstrings/errors"
+	"github.com/iawaknahc/gogenstrings/xmlplist"
+)
+
+// infoPlistLocalizableKeys are the keys in Info.plist
+// which can be localized in InfoPlist.strings
+// in addition to the usage descriptions.
+var infoPlistLocalizableKeys = map[string]bool{
+	"CFBundleDisplayName":      true,
+	"CFBundleName":             true,
+	"CFBundleSpokenName":       true,
+	"NSHumanReadableCopyright": true,
+}
+
+func isInfoPlistLocalizableKey(key string) bool {
+	if infoPlistLocalizableKeys[key] {
+		return true
+	}
+	// NSCameraUsageDescription, NFCReaderUsageDescription, etc.
+	return strings.HasSuffix(key, "UsageDescription")
+}
+
+func parseInfoPlist(src, filepath string) (entries, error) {
+	value, err := xmlplist.ParseXMLPlist(src, filepath)
+	if err != nil {
+		return nil, err
+	}
+
+	dict, ok := value.Value.(xmlplist.Dict)
+	if !ok {
+		return nil, errors.FileLineCol(
+			filepath,
+			value.Line,
+			value.Col,
+			"Info.plist is not a dict",
+		)
+	}
+
+	es := entries{}
+	for _, keyValue := range dict.Keys() {
+		key := keyValue.Value.(string)
+		if !isInfoPlistLocalizableKey(key) {
+			continue
+		}
+		valueValue, _ := dict.Get(key)
+		s, ok := valueValue.Value.(string)
+		if !ok {
+			return nil, errors.FileLineCol(
+				filepath,
+				valueValue.Line,
+				valueValue.Col,
+				"expected <string>",
+			)
+		}
+		// Build settings are expanded at build time
+		// and cannot be localized.
+		if strings.Contains(s, "$(") {
+			continue
+		}
+		e := entry{
+			filepath:  filepath,
+			startLine: keyValue.Line,
+			startCol:  keyValue.Col,
+			key:       key,
+			value:     s,
+		}
+		es = append(es, e)
+	}
+	return es.sort(), nil
+}
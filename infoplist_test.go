@@ -0,0 +1,68 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseInfoPlist(t *testing.T) {
+	input := `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>CFBundleName</key>
+	<string>$(PRODUCT_NAME)</string>
+	<key>CFBundleDisplayName</key>
+	<string>My App</string>
+	<key>CFBundleVersion</key>
+	<string>1</string>
+	<key>NSCameraUsageDescription</key>
+	<string>Use camera</string>
+</dict>
+</plist>
+`
+	expected := entries{
+		entry{
+			filepath:  "Info.plist",
+			startLine: 7,
+			startCol:  2,
+			key:       "CFBundleDisplayName",
+			value:     "My App",
+		},
+		entry{
+			filepath:  "Info.plist",
+			startLine: 11,
+			startCol:  2,
+			key:       "NSCameraUsageDescription",
+			value:     "Use camera",
+		},
+	}
+	actual, err := parseInfoPlist(input, "Info.plist")
+	if err != nil {
+		t.Errorf("%v\n", err)
+	} else if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("%v\n", actual)
+	}
+}
+
+func TestParseInfoPlistInvalid(t *testing.T) {
+	cases := []struct {
+		input string
+		msg   string
+	}{
+		{"<array></array>", ":3:22: Info.plist is not a dict"},
+		{"<dict><key>CFBundleName</key><true/></dict>", ":3:51: expected <string>"},
+	}
+	for _, c := range cases {
+		prefix := `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">`
+		suffix := "</plist>"
+		_, err := parseInfoPlist(prefix+c.input+suffix, "")
+		if err == nil {
+			t.Fail()
+		} else if err.Error() != c.msg {
+			t.Errorf("%v\n", err)
+		}
+	}
+}
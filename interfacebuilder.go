@@ -0,0 +1,370 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/iawaknahc/gogenstrings/errors"
+	"github.com/iawaknahc/gogenstrings/linecol"
+)
+
+const (
+	dotStoryboardExt = ".storyboard"
+	dotXIBExt        = ".xib"
+	baseLprojName    = "Base.lproj"
+)
+
+// interfaceBuilderClass is the class of an element
+// and its localizable attributes.
+type interfaceBuilderClass struct {
+	name       string
+	properties []string
+}
+
+// interfaceBuilderClasses are the localizable elements.
+// The key is the element name.
+var interfaceBuilderClasses = map[string]interfaceBuilderClass{
+	"label":            {"UILabel", []string{"text"}},
+	"button":           {"UIButton", nil},
+	"textField":        {"UITextField", []string{"text", "placeholder"}},
+	"textView":         {"UITextView", []string{"text"}},
+	"searchBar":        {"UISearchBar", []string{"text", "placeholder", "prompt"}},
+	"segmentedControl": {"UISegmentedControl", nil},
+	"navigationItem":   {"UINavigationItem", []string{"title", "prompt"}},
+	"barButtonItem":    {"UIBarButtonItem", []string{"title"}},
+	"tabBarItem":       {"UITabBarItem", []string{"title"}},
+	"tableViewSection": {"UITableViewSection", []string{"headerTitle", "footerTitle"}},
+	"window":           {"NSWindow", []string{"title"}},
+	"menu":             {"NSMenu", []string{"title"}},
+	"menuItem":         {"NSMenuItem", []string{"title"}},
+	"textFieldCell":    {"NSTextFieldCell", []string{"title", "placeholderString"}},
+	"buttonCell":       {"NSButtonCell", []string{"title"}},
+}
+
+func isInterfaceBuilderFile(fullpath string) bool {
+	ext := filepath.Ext(fullpath)
+	return ext == dotStoryboardExt || ext == dotXIBExt
+}
+
+// interfaceBuilderTable returns the table of the storyboard or XIB,
+// e.g. "Main" of "path/to/Base.lproj/Main.storyboard".
+func interfaceBuilderTable(fullpath string) string {
+	basename := filepath.Base(fullpath)
+	return strings.TrimSuffix(basename, filepath.Ext(basename))
+}
+
+// interfaceBuilderObject is a localizable element.
+type interfaceBuilderObject struct {
+	class     string
+	id        string
+	startLine int
+	startCol  int
+	// segment is the index of the next segment
+	// of UISegmentedControl.
+	segment int
+}
+
+type interfaceBuilderParser struct {
+	decoder   *xml.Decoder
+	offset    int
+	filepath  string
+	lineColer linecol.LineColer
+	entries   entries
+}
+
+func (p *interfaceBuilderParser) nextToken() xml.Token {
+	p.offset = int(p.decoder.InputOffset())
+	token, err := p.decoder.Token()
+	if err != nil {
+		if err == io.EOF {
+			return nil
+		}
+		line, col := p.lineColer.LineCol(p.offset)
+		if syntaxErr, ok := err.(*xml.SyntaxError); ok {
+			panic(errors.FileLineCol(p.filepath, line, col, syntaxErr.Msg))
+		}
+		panic(errors.FileLineCol(p.filepath, line, col, err.Error()))
+	}
+	return token
+}
+
+func (p *interfaceBuilderParser) recover(errp *error) {
+	if r := recover(); r != nil {
+		err, ok := r.(error)
+		if !ok {
+			panic("panicked without error")
+		}
+		*errp = err
+	}
+}
+
+func attrValue(start xml.StartElement, name string) string {
+	for _, attr := range start.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// add adds the property of object in the format of
+// ibtool --generate-strings-file.
+func (p *interfaceBuilderParser) add(object *interfaceBuilderObject, property, value string) {
+	if value == "" {
+		return
+	}
+	comment := fmt.Sprintf(
+		"Class = %v; %v = %v; ObjectID = %v;",
+		PrintASCIIPlistString(object.class),
+		property,
+		PrintASCIIPlistString(value),
+		PrintASCIIPlistString(object.id),
+	)
+	p.entries = append(p.entries, entry{
+		filepath:  p.filepath,
+		startLine: object.startLine,
+		startCol:  object.startCol,
+		comment:   comment,
+		key:       object.id + "." + property,
+		value:     value,
+	})
+}
+
+// charData returns the text content of the current element.
+func (p *interfaceBuilderParser) charData(start xml.StartElement) string {
+	buf := strings.Builder{}
+	for {
+		token := p.nextToken()
+		switch t := token.(type) {
+		case nil:
+			line, col := p.lineColer.LineCol(p.offset)
+			panic(errors.FileLineCol(p.filepath, line, col, fmt.Sprintf("unexpected EOF; expected </%v>", start.Name.Local)))
+		case xml.CharData:
+			buf.Write(t)
+		case xml.EndElement:
+			return buf.String()
+		}
+	}
+}
+
+// parseElement parses start and its children.
+// owner is the localizable element which start belongs to.
+func (p *interfaceBuilderParser) parseElement(start xml.StartElement, owner *interfaceBuilderObject) {
+	var object *interfaceBuilderObject
+	name := start.Name.Local
+	switch {
+	case owner != nil && name == "state":
+		// <state key="normal" title="..."/> of UIButton
+		p.add(owner, attrValue(start, "key")+"Title", attrValue(start, "title"))
+	case owner != nil && name == "segments":
+		object = owner
+	case owner != nil && name == "segment":
+		p.add(owner, fmt.Sprintf("segmentTitles[%v]", owner.segment), attrValue(start, "title"))
+		owner.segment++
+	case owner != nil && name == "accessibility":
+		p.add(owner, "accessibilityLabel", attrValue(start, "label"))
+		p.add(owner, "accessibilityHint", attrValue(start, "hint"))
+	case owner != nil && name == "string":
+		// Multiline text is <string key="text">...</string>
+		p.add(owner, attrValue(start, "key"), p.charData(start))
+		return
+	default:
+		class, ok := interfaceBuilderClasses[name]
+		id := attrValue(start, "id")
+		if ok && id != "" {
+			object = &interfaceBuilderObject{
+				class: class.name,
+				id:    id,
+			}
+			object.startLine, object.startCol = p.lineColer.LineCol(p.offset)
+			for _, property := range class.properties {
+				p.add(object, property, attrValue(start, property))
+			}
+		}
+	}
+	for {
+		token := p.nextToken()
+		switch t := token.(type) {
+		case nil:
+			line, col := p.lineColer.LineCol(p.offset)
+			panic(errors.FileLineCol(p.filepath, line, col, fmt.Sprintf("unexpected EOF; expected </%v>", name)))
+		case xml.EndElement:
+			return
+		case xml.StartElement:
+			p.parseElement(t, object)
+		}
+	}
+}
+
+// parseInterfaceBuilder returns the localizable properties
+// of a storyboard or XIB keyed by ObjectID.property.
+func parseInterfaceBuilder(src, filepath string) (out entries, err error) {
+	p := &interfaceBuilderParser{
+		decoder:   xml.NewDecoder(strings.NewReader(src)),
+		filepath:  filepath,
+		lineColer: linecol.NewLineColer(src),
+		entries:   entries{},
+	}
+	defer p.recover(&err)
+	for {
+		token := p.nextToken()
+		switch t := token.(type) {
+		case nil:
+			return p.entries, nil
+		case xml.StartElement:
+			p.parseElement(t, nil)
+		}
+	}
+}
+
+// findInterfaceBuilderFiles finds the storyboards and XIBs in Base.lproj.
+// In -xcodeproj mode, they are found in findXcodeproj instead.
+func (p *genstringsContext) findInterfaceBuilderFiles() error {
+	if !p.interfaceBuilder || p.xcodeprojPath != "" {
+		return nil
+	}
+	walkFn := func(fullpath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() || !isInterfaceBuilderFile(fullpath) {
+			return nil
+		}
+		if filepath.Base(filepath.Dir(fullpath)) != baseLprojName {
+			return nil
+		}
+		if p.excludeRegexp == nil || !p.excludeRegexp.MatchString(fullpath) {
+			p.interfaceBuilderPaths = append(p.interfaceBuilderPaths, fullpath)
+		}
+		return nil
+	}
+	if err := filepath.Walk(p.rootPath, walkFn); err != nil {
+		return err
+	}
+	sort.Strings(p.interfaceBuilderPaths)
+	// The lprojs are next to Base.lproj.
+	for _, ibPath := range p.interfaceBuilderPaths {
+		parent := filepath.Dir(filepath.Dir(ibPath))
+		lprojs := []string{}
+		for _, lproj := range p.lprojs {
+			if filepath.Dir(lproj) == parent {
+				lprojs = append(lprojs, lproj)
+			}
+		}
+		p.interfaceBuilderLprojs[ibPath] = lprojs
+	}
+	return nil
+}
+
+// findXcodeprojInterfaceBuilderFiles finds the storyboards and XIBs
+// in the resources of target. Their lprojs are the known regions
+// next to Base.lproj.
+func (p *genstringsContext) findXcodeprojInterfaceBuilderFiles(proj pbxproj, target map[string]interface{}, synchronized []string) {
+	if !p.interfaceBuilder {
+		return
+	}
+	for _, ibPath := range proj.interfaceBuilderFiles(target, synchronized) {
+		if p.excludeRegexp != nil && p.excludeRegexp.MatchString(ibPath) {
+			continue
+		}
+		p.interfaceBuilderPaths = append(p.interfaceBuilderPaths, ibPath)
+		parent := filepath.Dir(filepath.Dir(ibPath))
+		lprojs := []string{}
+		for _, region := range proj.knownRegions() {
+			lproj := filepath.Join(parent, region+".lproj")
+			if info, err := os.Stat(lproj); err == nil && info.IsDir() {
+				lprojs = append(lprojs, lproj)
+			}
+		}
+		p.interfaceBuilderLprojs[ibPath] = lprojs
+	}
+	sort.Strings(p.interfaceBuilderPaths)
+}
+
+// interfaceBuilderDevLproj returns the lproj of the development language
+// of the storyboard or XIB.
+func (p *genstringsContext) interfaceBuilderDevLproj(ibPath string) string {
+	for _, lproj := range p.interfaceBuilderLprojs[ibPath] {
+		if filepath.Base(lproj) == p.devlang+".lproj" {
+			return lproj
+		}
+	}
+	return ""
+}
+
+func (p *genstringsContext) readInterfaceBuilderFiles() {
+	for _, ibPath := range p.interfaceBuilderPaths {
+		lprojs := p.interfaceBuilderLprojs[ibPath]
+		devLproj := p.interfaceBuilderDevLproj(ibPath)
+		if devLproj == "" {
+			parent := filepath.Dir(filepath.Dir(ibPath))
+			p.diagnostics.Add(errors.File(filepath.Join(parent, p.devlang+".lproj"), "directory not found"))
+			continue
+		}
+		content, err := readFile(ibPath)
+		if err != nil {
+			p.diagnostics.Add(err)
+			continue
+		}
+		es, err := parseInterfaceBuilder(content, ibPath)
+		if err != nil {
+			p.diagnostics.Add(err)
+			continue
+		}
+		p.interfaceBuilderEntries[ibPath] = es
+		in := make(map[string]entries)
+		p.readDotStringsIn(lprojs, interfaceBuilderTable(ibPath)+dotStringsExt, in)
+		p.inInterfaceBuilderEntries[ibPath] = in
+	}
+}
+
+func (p *genstringsContext) validateInterfaceBuilderFiles() {
+	// Main.storyboard and Main.xib in the same Base.lproj
+	// would be written to the same Main.strings.
+	tables := make(map[string]string)
+	for _, ibPath := range p.interfaceBuilderPaths {
+		targetPath := filepath.Join(filepath.Dir(filepath.Dir(ibPath)), interfaceBuilderTable(ibPath))
+		if existing, ok := tables[targetPath]; ok {
+			p.diagnostics.Add(errors.File(ibPath, fmt.Sprintf("table `%v` is also used by %v", interfaceBuilderTable(ibPath), existing)))
+			continue
+		}
+		tables[targetPath] = ibPath
+	}
+
+	for ibPath, es := range p.interfaceBuilderEntries {
+		em, err := es.toEntryMap()
+		p.diagnostics.Add(err)
+		p.interfaceBuilderEntryMap[ibPath] = em
+
+		table := interfaceBuilderTable(ibPath)
+		if _, ok := p.routineCallByKey[table]; ok {
+			p.diagnostics.Add(errors.File(ibPath, fmt.Sprintf("table `%v` is also used in source code", table)))
+		}
+
+		out := make(map[string]entryMap)
+		p.validateDotStrings(p.inInterfaceBuilderEntries[ibPath], out)
+		p.inInterfaceBuilderEntryMap[ibPath] = out
+	}
+}
+
+// processInterfaceBuilderFiles regenerates the development language
+// like ibtool does and keeps the existing translations.
+func (p *genstringsContext) processInterfaceBuilderFiles() {
+	for ibPath, inEntryMap := range p.inInterfaceBuilderEntryMap {
+		devLproj := p.interfaceBuilderDevLproj(ibPath)
+		out := make(map[string]entryMap)
+		out[devLproj] = p.interfaceBuilderEntryMap[ibPath]
+		for lproj, em := range inEntryMap {
+			if lproj == devLproj {
+				continue
+			}
+			out[lproj] = em.mergeDev(out[devLproj])
+		}
+		p.outInterfaceBuilderEntryMap[ibPath] = out
+	}
+}
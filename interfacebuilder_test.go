@@ -0,0 +1,195 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testStoryboard = `<?xml version="1.0" encoding="UTF-8"?>
+<document type="com.apple.InterfaceBuilder3.CocoaTouch.Storyboard.XIB" version="3.0">
+    <scenes>
+        <scene sceneID="s1">
+            <objects>
+                <viewController id="vc1" sceneMemberID="viewController">
+                    <view key="view" contentMode="scaleToFill" id="v1">
+                        <subviews>
+                            <label text="Hello" id="lbl-1">
+                                <accessibility key="accessibilityConfiguration" label="Greeting"/>
+                            </label>
+                            <label id="lbl-2">
+                                <string key="text">Line 1
+Line 2</string>
+                            </label>
+                            <button id="btn-1">
+                                <state key="normal" title="Tap"/>
+                                <state key="highlighted" title="Tapped"/>
+                            </button>
+                            <textField placeholder="Name" id="tf-1"/>
+                            <segmentedControl id="seg-1">
+                                <segments>
+                                    <segment title="First"/>
+                                    <segment title="Second"/>
+                                </segments>
+                            </segmentedControl>
+                        </subviews>
+                    </view>
+                    <navigationItem key="navigationItem" title="Home" id="nav-1"/>
+                </viewController>
+            </objects>
+        </scene>
+    </scenes>
+</document>
+`
+
+func TestParseInterfaceBuilder(t *testing.T) {
+	es, err := parseInterfaceBuilder(testStoryboard, "Main.storyboard")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	expected := []struct {
+		key     string
+		value   string
+		comment string
+		line    int
+	}{
+		{"btn-1.highlightedTitle", "Tapped", `Class = "UIButton"; highlightedTitle = "Tapped"; ObjectID = "btn-1";`, 16},
+		{"btn-1.normalTitle", "Tap", `Class = "UIButton"; normalTitle = "Tap"; ObjectID = "btn-1";`, 16},
+		{"lbl-1.accessibilityLabel", "Greeting", `Class = "UILabel"; accessibilityLabel = "Greeting"; ObjectID = "lbl-1";`, 9},
+		{"lbl-1.text", "Hello", `Class = "UILabel"; text = "Hello"; ObjectID = "lbl-1";`, 9},
+		{"lbl-2.text", "Line 1\nLine 2", `Class = "UILabel"; text = "Line 1\nLine 2"; ObjectID = "lbl-2";`, 12},
+		{"nav-1.title", "Home", `Class = "UINavigationItem"; title = "Home"; ObjectID = "nav-1";`, 29},
+		{"seg-1.segmentTitles[0]", "First", `Class = "UISegmentedControl"; segmentTitles[0] = "First"; ObjectID = "seg-1";`, 21},
+		{"seg-1.segmentTitles[1]", "Second", `Class = "UISegmentedControl"; segmentTitles[1] = "Second"; ObjectID = "seg-1";`, 21},
+		{"tf-1.placeholder", "Name", `Class = "UITextField"; placeholder = "Name"; ObjectID = "tf-1";`, 20},
+	}
+	sorted := es.sort()
+	if len(sorted) != len(expected) {
+		t.Fatalf("%+v\n", sorted)
+	}
+	for i, e := range sorted {
+		c := expected[i]
+		if e.key != c.key || e.value != c.value || e.comment != c.comment || e.startLine != c.line {
+			t.Errorf("%+v\n", e)
+		}
+	}
+
+	_, err = parseInterfaceBuilder("<document>\n<label id=\"a\"></document>", "Main.storyboard")
+	if err == nil || err.Error() != "Main.storyboard:2:15: element <label> closed by </document>" {
+		t.Errorf("%v\n", err)
+	}
+}
+
+func TestInterfaceBuilder(t *testing.T) {
+	root, err := ioutil.TempDir("", "gogenstrings")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	defer os.RemoveAll(root)
+
+	writeFiles(t, root, map[string]string{
+		"Base.lproj/Main.storyboard": `<document><label text="Hello" id="a"/><label text="World" id="b"/></document>`,
+		"en.lproj/Main.strings":      "\"a.text\" = \"Old\";\n",
+		"ja.lproj/Main.strings":      "\"a.text\" = \"こんにちは\";\n\"c.text\" = \"削除\";\n",
+	})
+
+	ctx := newGenstringsContext(genstringsOptions{
+		rootPath:         root,
+		devlang:          "en",
+		routineName:      "NSLocalizedString",
+		interfaceBuilder: true,
+	})
+	if err := ctx.genstrings(); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	expected := map[string]string{
+		"en.lproj/Main.strings": `/* Class = "UILabel"; text = "Hello"; ObjectID = "a"; */
+"a.text" = "Hello";
+
+/* Class = "UILabel"; text = "World"; ObjectID = "b"; */
+"b.text" = "World";
+
+`,
+		"ja.lproj/Main.strings": `/* Class = "UILabel"; text = "Hello"; ObjectID = "a"; */
+"a.text" = "こんにちは";
+
+/* Class = "UILabel"; text = "World"; ObjectID = "b"; */
+"b.text" = "World";
+
+`,
+	}
+	for name, content := range expected {
+		if actual, err := readFile(filepath.Join(root, name)); err != nil || actual != content {
+			t.Errorf("%v: %q %v\n", name, actual, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, "Base.lproj/Main.strings")); !os.IsNotExist(err) {
+		t.Errorf("%v\n", err)
+	}
+}
+
+func TestXcodeprojInterfaceBuilder(t *testing.T) {
+	root, err := ioutil.TempDir("", "gogenstrings")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	defer os.RemoveAll(root)
+
+	writeFiles(t, root, map[string]string{
+		"App.xcodeproj/project.pbxproj":    testPBXProj,
+		"App/A.swift":                      "",
+		"App/Base.lproj/Main.storyboard":   `<document><label text="Hello" id="a"/></document>`,
+		"App/en.lproj/Localizable.strings": "",
+		"App/ja.lproj/Localizable.strings": "",
+		// Not in the target
+		"Other/Base.lproj/Other.storyboard": `<document><label text="Other" id="o"/></document>`,
+	})
+
+	ctx := newGenstringsContext(genstringsOptions{
+		rootPath:         root,
+		routineName:      "NSLocalizedString",
+		xcodeprojPath:    filepath.Join(root, "App.xcodeproj"),
+		xcodeprojTarget:  "App",
+		interfaceBuilder: true,
+	})
+	if err := ctx.genstrings(); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	expected := "/* Class = \"UILabel\"; text = \"Hello\"; ObjectID = \"a\"; */\n\"a.text\" = \"Hello\";\n\n"
+	for _, name := range []string{"App/en.lproj/Main.strings", "App/ja.lproj/Main.strings"} {
+		if actual, err := readFile(filepath.Join(root, name)); err != nil || actual != expected {
+			t.Errorf("%v: %q %v\n", name, actual, err)
+		}
+	}
+}
+
+func TestInterfaceBuilderTableCollision(t *testing.T) {
+	root, err := ioutil.TempDir("", "gogenstrings")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	defer os.RemoveAll(root)
+
+	writeFiles(t, root, map[string]string{
+		"Base.lproj/Main.storyboard":   `<document><label text="Hello" id="a"/></document>`,
+		"Base.lproj/Main.xib":          `<document><label text="World" id="b"/></document>`,
+		"en.lproj/Localizable.strings": "",
+	})
+
+	ctx := newGenstringsContext(genstringsOptions{
+		rootPath:         root,
+		devlang:          "en",
+		routineName:      "NSLocalizedString",
+		interfaceBuilder: true,
+	})
+	err = ctx.genstrings()
+	expected := filepath.Join(root, "Base.lproj/Main.xib") + ": table `Main` is also used by " + filepath.Join(root, "Base.lproj/Main.storyboard")
+	if err == nil || err.Error() != expected {
+		t.Errorf("%v\n", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "en.lproj/Main.strings")); !os.IsNotExist(err) {
+		t.Errorf("%v\n", err)
+	}
+}
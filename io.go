@@ -1,26 +1,135 @@
 package main
 
 import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
 	"io/ioutil"
+	"unicode/utf16"
 	"unicode/utf8"
 
 	"github.com/iawaknahc/gogenstrings/errors"
 )
 
+// encoding is the text encoding of a file.
+// UTF-16 is always written with BOM.
+// UTF-8 is written with BOM only if it is encodingUTF8BOM.
+type encoding string
+
+const (
+	encodingUTF8    encoding = "utf-8"
+	encodingUTF8BOM encoding = "utf-8-bom"
+	encodingUTF16LE encoding = "utf-16le"
+	encodingUTF16BE encoding = "utf-16be"
+)
+
+// encodingPreserve means writing in the encoding of the existing file.
+const encodingPreserve = "preserve"
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+func parseEncoding(s string) (encoding, error) {
+	switch enc := encoding(s); enc {
+	case encodingUTF8, encodingUTF8BOM, encodingUTF16LE, encodingUTF16BE:
+		return enc, nil
+	}
+	return "", fmt.Errorf("unknown encoding `%v`", s)
+}
+
+// decode detects the encoding of b by its BOM.
+// Without BOM, b must be UTF-8.
+func decode(filename string, b []byte) (string, encoding, error) {
+	var order binary.ByteOrder
+	enc := encodingUTF8
+	switch {
+	case bytes.HasPrefix(b, bomUTF8):
+		enc = encodingUTF8BOM
+		b = b[len(bomUTF8):]
+	case bytes.HasPrefix(b, bomUTF16LE):
+		order = binary.LittleEndian
+		enc = encodingUTF16LE
+		b = b[len(bomUTF16LE):]
+	case bytes.HasPrefix(b, bomUTF16BE):
+		order = binary.BigEndian
+		enc = encodingUTF16BE
+		b = b[len(bomUTF16BE):]
+	}
+
+	if order == nil {
+		if !utf8.Valid(b) {
+			return "", "", errors.File(filename, "file is not UTF-8 encoded")
+		}
+		return string(b), enc, nil
+	}
+
+	if len(b)%2 != 0 {
+		return "", "", errors.File(filename, "file is not UTF-16 encoded")
+	}
+	units := make([]uint16, len(b)/2)
+	for i := range units {
+		units[i] = order.Uint16(b[2*i:])
+	}
+	// utf16.Decode replaces unpaired surrogates silently.
+	for i := 0; i < len(units); i++ {
+		r := rune(units[i])
+		if !utf16.IsSurrogate(r) {
+			continue
+		}
+		if i+1 >= len(units) || utf16.DecodeRune(r, rune(units[i+1])) == utf8.RuneError {
+			return "", "", errors.File(filename, "file is not UTF-16 encoded")
+		}
+		i++
+	}
+	return string(utf16.Decode(units)), enc, nil
+}
+
+func encode(content string, enc encoding) []byte {
+	var order binary.ByteOrder
+	var bom []byte
+	switch enc {
+	case encodingUTF16LE:
+		order = binary.LittleEndian
+		bom = bomUTF16LE
+	case encodingUTF16BE:
+		order = binary.BigEndian
+		bom = bomUTF16BE
+	case encodingUTF8BOM:
+		out := make([]byte, len(bomUTF8)+len(content))
+		copy(out, bomUTF8)
+		copy(out[len(bomUTF8):], content)
+		return out
+	default:
+		return []byte(content)
+	}
+	units := utf16.Encode([]rune(content))
+	out := make([]byte, len(bom)+2*len(units))
+	copy(out, bom)
+	for i, u := range units {
+		order.PutUint16(out[len(bom)+2*i:], u)
+	}
+	return out
+}
+
 func readFile(filename string) (string, error) {
-	bytes, err := ioutil.ReadFile(filename)
+	content, _, err := readFileEncoding(filename)
+	return content, err
+}
+
+func readFileEncoding(filename string) (string, encoding, error) {
+	b, err := ioutil.ReadFile(filename)
 	if err != nil {
-		return "", err
-	}
-	if !utf8.Valid(bytes) {
-		return "", errors.File(filename, "file is not UTF-8 encoded")
+		return "", "", err
 	}
-	return string(bytes), nil
+	return decode(filename, b)
 }
 
-func writeFile(filename, content string) error {
+func writeFile(filename, content string, enc encoding) error {
 	// Write the file directly instead of
 	// Writing to the temp file followed by a rename
 	// in order to avoid cross-device link
-	return ioutil.WriteFile(filename, []byte(content), 0644)
+	return ioutil.WriteFile(filename, encode(content, enc), 0644)
 }
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	cases := []struct {
+		input    []byte
+		expected string
+		enc      encoding
+	}{
+		{[]byte("a = b;"), "a = b;", encodingUTF8},
+		{[]byte("\xEF\xBB\xBFa"), "a", encodingUTF8BOM},
+		{[]byte("\xFF\xFEa\x00=\x00"), "a=", encodingUTF16LE},
+		{[]byte("\xFE\xFF\x00a\x00="), "a=", encodingUTF16BE},
+		{[]byte("\xFF\xFE\x3D\xD8\x00\xDE"), "😀", encodingUTF16LE},
+	}
+	for _, c := range cases {
+		actual, enc, err := decode("a.strings", c.input)
+		if err != nil {
+			t.Errorf("%q: %v\n", c.input, err)
+		} else if actual != c.expected || enc != c.enc {
+			t.Errorf("%q: %q %v\n", c.input, actual, enc)
+		}
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	cases := []struct {
+		input    []byte
+		expected string
+	}{
+		{[]byte("\xFFa"), "a.strings: file is not UTF-8 encoded"},
+		{[]byte("\xFF\xFEa"), "a.strings: file is not UTF-16 encoded"},
+		{[]byte("\xFF\xFE\x3D\xD8a\x00"), "a.strings: file is not UTF-16 encoded"},
+		{[]byte("\xFF\xFE\x3D\xD8"), "a.strings: file is not UTF-16 encoded"},
+	}
+	for _, c := range cases {
+		_, _, err := decode("a.strings", c.input)
+		if err == nil || err.Error() != c.expected {
+			t.Errorf("%q: %v\n", c.input, err)
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	cases := []struct {
+		input    string
+		enc      encoding
+		expected []byte
+	}{
+		{"a😀", encodingUTF8, []byte("a😀")},
+		{"a😀", encodingUTF8BOM, []byte("\xEF\xBB\xBFa😀")},
+		{"a😀", encodingUTF16LE, []byte("\xFF\xFEa\x00\x3D\xD8\x00\xDE")},
+		{"a😀", encodingUTF16BE, []byte("\xFE\xFF\x00a\xD8\x3D\xDE\x00")},
+	}
+	for _, c := range cases {
+		actual := encode(c.input, c.enc)
+		if !bytes.Equal(actual, c.expected) {
+			t.Errorf("%v: %q\n", c.enc, actual)
+		}
+	}
+}
+
+func TestParseEncoding(t *testing.T) {
+	if enc, err := parseEncoding("utf-16le"); err != nil || enc != encodingUTF16LE {
+		t.Errorf("%v %v\n", enc, err)
+	}
+	if _, err := parseEncoding("utf-32"); err == nil || err.Error() != "unknown encoding `utf-32`" {
+		t.Errorf("%v\n", err)
+	}
+}
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
+	"regexp"
 	"strconv"
 	"strings"
 	"unicode/utf16"
@@ -21,6 +23,7 @@ const (
 	itemComment
 	itemSpaces
 	itemString
+	itemInterpolatedString
 	itemBareString
 	itemEqualSign
 	itemSemicolon
@@ -48,6 +51,8 @@ func (v itemType) String() string {
 		return "<space>"
 	case itemString:
 		return "<string>"
+	case itemInterpolatedString:
+		return "<interpolated-string>"
 	case itemBareString:
 		return "<bare-string>"
 	case itemEqualSign:
@@ -97,40 +102,44 @@ func (v lexItem) String() string {
 }
 
 func (v lexItem) unexpectedTokenErr() errors.ErrFileLineCol {
-	return errors.FileLineCol(
+	err := errors.FileLineCol(
 		v.Filepath,
 		v.StartLine,
 		v.StartCol,
 		fmt.Sprintf("unexpected token `%v`", v.Type),
 	)
+	if v.EndLine > 0 {
+		err = err.WithEnd(v.EndLine, v.EndCol)
+	}
+	return err
 }
 
 type stateFn func(*lexer) stateFn
 
-// runeStack is for handling
-// Swift's String Interpolation syntax
-type runeStack []rune
-
-func (s runeStack) push(r rune) runeStack {
-	return append(s, r)
+// syntax tells the lexer how to lex
+// string literals and comments in source code.
+type syntax struct {
+	lexString       func(stateFn) stateFn
+	lexBlockComment func(stateFn) stateFn
+	// extendedDelimiter tells if string literals
+	// can have extended delimiters like #"..."#.
+	extendedDelimiter bool
 }
 
-func (s runeStack) peek() (rune, bool) {
-	l := len(s)
-	if l <= 0 {
-		return 0, false
-	}
-	return s[l-1], true
+var swiftSyntax = syntax{
+	lexString:         lexStringSwift,
+	lexBlockComment:   lexNestedComment,
+	extendedDelimiter: true,
 }
 
-func (s runeStack) pop() runeStack {
-	l := len(s)
-	return s[:l-1]
+var objcSyntax = syntax{
+	lexString:       lexStringObjc,
+	lexBlockComment: lexComment,
 }
 
 type lexer struct {
 	state     stateFn
-	lexString func(stateFn) stateFn
+	syntax    syntax
 	filepath  string
 	input     string
 	start     int
@@ -140,10 +149,10 @@ type lexer struct {
 	items     chan lexItem
 }
 
-func newLexerWithString(input, filepath string, lexString func(stateFn) stateFn, state stateFn) lexer {
+func newLexerWithSyntax(input, filepath string, syntax syntax, state stateFn) lexer {
 	l := lexer{
 		state:     state,
-		lexString: lexString,
+		syntax:    syntax,
 		filepath:  filepath,
 		lineColer: linecol.NewLineColer(input),
 		input:     input,
@@ -154,11 +163,19 @@ func newLexerWithString(input, filepath string, lexString func(stateFn) stateFn,
 }
 
 func newLexer(input, filepath string, state stateFn) lexer {
-	return newLexerWithString(input, filepath, nil, state)
+	return newLexerWithSyntax(input, filepath, syntax{}, state)
 }
 
 func (l *lexer) nextItem() lexItem {
-	item := <-l.items
+	item, ok := <-l.items
+	if !ok {
+		// The lexer has finished.
+		// Keep reporting EOF to the parser.
+		return lexItem{
+			Type:     itemEOF,
+			Filepath: l.filepath,
+		}
+	}
 	return item
 }
 
@@ -216,6 +233,10 @@ func (l *lexer) emitValue(typ itemType, value string) {
 	l.items <- item
 }
 
+func (l *lexer) unterminatedComment() stateFn {
+	return l.emitError("unterminated comment", true)
+}
+
 func (l *lexer) unterminatedStringLiteral() stateFn {
 	return l.emitError("unterminated string literal", true)
 }
@@ -240,12 +261,29 @@ func (l *lexer) invalidStringInterpolation() stateFn {
 	return l.emitError("invalid string interpolation", true)
 }
 
+func (l *lexer) multilineStringContentNotOnNewLine() stateFn {
+	return l.emitError("multi-line string literal content must begin on a new line", true)
+}
+
+func (l *lexer) multilineStringClosingDelimiterNotOnNewLine() stateFn {
+	return l.emitError("multi-line string literal closing delimiter must begin on a new line", true)
+}
+
+func (l *lexer) insufficientIndentation() stateFn {
+	return l.emitError("insufficient indentation of line in multi-line string literal", true)
+}
+
 func (l *lexer) emitError(msg string, atStart bool) stateFn {
 	startLine, startCol := l.lineCol(l.start)
 	endLine, endCol := l.lineCol(l.pos)
 	var err error
 	if atStart {
-		err = errors.FileLineCol(l.filepath, startLine, startCol, msg)
+		e := errors.FileLineCol(l.filepath, startLine, startCol, msg)
+		// The end is unknown if the error is at EOF.
+		if endLine > 0 {
+			e = e.WithEnd(endLine, endCol)
+		}
+		err = e
 	} else {
 		err = errors.FileLineCol(l.filepath, endLine, endCol-1, msg)
 	}
@@ -346,7 +384,57 @@ func lexComment(state stateFn) stateFn {
 				return state
 			}
 			if r := l.next(); r == eof {
-				return l.unexpectedToken(r)
+				return l.unterminatedComment()
+			}
+		}
+	}
+}
+
+// lexNestedComment is like lexComment but
+// block comments can be nested, like Swift.
+func lexNestedComment(state stateFn) stateFn {
+	return func(l *lexer) stateFn {
+		l.next()
+		l.next()
+		depth := 1
+		for {
+			if strings.HasPrefix(l.input[l.pos:], "/*") {
+				l.next()
+				l.next()
+				depth++
+				continue
+			}
+			if strings.HasPrefix(l.input[l.pos:], "*/") {
+				l.next()
+				l.next()
+				depth--
+				if depth <= 0 {
+					value := l.input[l.start+2 : l.pos-2]
+					l.emitValue(itemComment, value)
+					return state
+				}
+				continue
+			}
+			if r := l.next(); r == eof {
+				return l.unterminatedComment()
+			}
+		}
+	}
+}
+
+func lexLineComment(state stateFn) stateFn {
+	return func(l *lexer) stateFn {
+		l.next()
+		l.next()
+		for {
+			r := l.next()
+			if r == eof || r == '\n' || r == '\r' {
+				if r != eof {
+					l.backup()
+				}
+				value := l.input[l.start+2 : l.pos]
+				l.emitValue(itemComment, value)
+				return state
 			}
 		}
 	}
@@ -409,146 +497,346 @@ func lexOctalDigits(l *lexer, min, max int) (rune, bool) {
 	return rune(value), true
 }
 
-func skipStringInterpolation(state stateFn) stateFn {
-	return func(l *lexer) stateFn {
-		stack := runeStack{}
-		stack = stack.push('"')
-		stack = stack.push('(')
-		for {
-			var inExpr bool
-			lastPushedRune, ok := stack.peek()
-			if !ok {
-				return l.invalidStringInterpolation()
+// skipStringInterpolation skips the expression of
+// a string interpolation after \( up to and including the matching ).
+// String literals in the expression can be in any form.
+// The expression can span multiple lines only in multi-line string literal.
+func skipStringInterpolation(l *lexer, multiline bool) bool {
+	depth := 1
+	for {
+		r := l.next()
+		switch r {
+		case eof:
+			return false
+		case '\n', '\r':
+			if !multiline {
+				return false
 			}
-			inExpr = lastPushedRune == '('
-
-			r := l.next()
-			if r == eof || r == '\n' || r == '\r' {
-				return l.invalidStringInterpolation()
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth <= 0 {
+				return true
 			}
-
-			if inExpr {
-				switch r {
-				case '(':
-					stack = stack.push('(')
-				case ')':
-					if last, ok := stack.peek(); !ok || last != '(' {
-						return l.invalidStringInterpolation()
-					}
-					stack = stack.pop()
-				case '"':
-					stack = stack.push('"')
-				default:
-					break
-				}
-			} else {
-				switch r {
-				case '"':
-					if last, ok := stack.peek(); !ok || last != '"' {
-						return l.invalidStringInterpolation()
-					}
-					stack = stack.pop()
-					if len(stack) <= 0 {
-						l.ignore()
-						return state
-					}
-				case '\\':
-					nextRune := l.next()
-					switch nextRune {
-					case eof, '\n', '\r':
-						return l.invalidStringInterpolation()
-					case '(':
-						stack = stack.push('(')
-					default:
-						break
-					}
-				default:
-					break
-				}
+		case '"', '#':
+			if !isSwiftStringStart(l.input[l.pos-1:]) {
+				break
 			}
+			l.backup()
+			if _, _, errFn := scanStringSwift(l); errFn != nil {
+				return false
+			}
+		}
+	}
+}
+
+// stringInterpolation is an interpolation in a string literal.
+type stringInterpolation struct {
+	// index is the index of the literal runes where it appears.
+	index     int
+	specifier string
+}
+
+var specifierRegexp = regexp.MustCompile(`,\s*specifier:\s*"([^"\\]*)"\s*$`)
+
+// formatSpecifierOfInterpolation returns the format specifier
+// of the interpolation expression.
+// The expression is like `count, specifier: "%d"` in SwiftUI.
+// Otherwise it is %@ because the type of the expression is unknown.
+func formatSpecifierOfInterpolation(expr string) string {
+	if m := specifierRegexp.FindStringSubmatch(expr); m != nil {
+		return m[1]
+	}
+	return "%@"
+}
+
+// formatInterpolatedString turns the literal runes and the interpolations
+// into a format string, like what Xcode does to LocalizedStringKey.
+func formatInterpolatedString(runes []rune, interpolations []stringInterpolation) string {
+	buf := bytes.Buffer{}
+	i := 0
+	for index, r := range runes {
+		for i < len(interpolations) && interpolations[i].index == index {
+			buf.WriteString(interpolations[i].specifier)
+			i++
+		}
+		if r == '%' {
+			buf.WriteString("%%")
+		} else {
+			buf.WriteRune(r)
 		}
 	}
+	for ; i < len(interpolations); i++ {
+		buf.WriteString(interpolations[i].specifier)
+	}
+	return buf.String()
 }
 
 func lexStringSwift(state stateFn) stateFn {
 	// https://github.com/apple/swift/blob/master/lib/Parse/Lexer.cpp
 	return func(l *lexer) stateFn {
+		runes, interpolations, errFn := scanStringSwift(l)
+		if errFn != nil {
+			return errFn()
+		}
+		if len(interpolations) > 0 {
+			l.emitValue(itemInterpolatedString, formatInterpolatedString(runes, interpolations))
+		} else {
+			l.emitValue(itemString, string(runes))
+		}
+		return state
+	}
+}
+
+// isSwiftStringStart tells if s begins with
+// a Swift string literal, possibly with extended delimiter.
+func isSwiftStringStart(s string) bool {
+	return strings.HasPrefix(strings.TrimLeft(s, "#"), `"`)
+}
+
+func isHorizontalSpace(r rune) bool {
+	return r == ' ' || r == '\t'
+}
+
+// skipNewline consumes \n, \r or \r\n.
+func skipNewline(l *lexer) bool {
+	switch l.next() {
+	case '\n':
+		return true
+	case '\r':
+		if l.peek() == '\n' {
+			l.next()
+		}
+		return true
+	case eof:
+		return false
+	}
+	l.backup()
+	return false
+}
+
+// skipHorizontalSpaces consumes spaces and tabs and returns them.
+func skipHorizontalSpaces(l *lexer) string {
+	start := l.pos
+	for {
+		r := l.next()
+		if !isHorizontalSpace(r) {
+			if r != eof {
+				l.backup()
+			}
+			return l.input[start:l.pos]
+		}
+	}
+}
+
+// scanStringSwift scans a Swift string literal in any form,
+// that is, "...", """...""", #"..."#, #"""..."""#, etc.
+// On error, errFn is the function emitting the error.
+func scanStringSwift(l *lexer) (runes []rune, interpolations []stringInterpolation, errFn func() stateFn) {
+	delimiter := 0
+	for l.peek() == '#' {
 		l.next()
-		runes := []rune{}
-		for {
-			r := l.next()
-			switch r {
-			case eof, '\n', '\r':
-				return l.unterminatedStringLiteral()
-			case '"':
-				l.emitValue(itemString, string(runes))
-				return state
-			case '\\':
-				nextRune := l.next()
-				switch nextRune {
-				case eof:
-					return l.unterminatedStringLiteral()
-				case '(':
-					return skipStringInterpolation(state)
-				case '\\':
-					runes = append(runes, '\\')
-				case '0':
-					runes = append(runes, rune(0))
-				case 't':
-					runes = append(runes, '\t')
-				case 'r':
-					runes = append(runes, '\r')
-				case 'n':
-					runes = append(runes, '\n')
-				case '\'':
-					runes = append(runes, '\'')
-				case '"':
-					runes = append(runes, '"')
-				case 'u':
-					leftBrace := l.next()
-					if leftBrace != '{' {
-						return l.invalidUnicodeEscape()
-					}
-					hexDigits := []rune{}
-				Loop:
-					for i := 0; i < 8; i++ {
-						hexDigit := l.next()
-						switch hexDigit {
-						case '}':
-							l.backup()
-							break Loop
-						default:
-							if !isHex(hexDigit) {
-								return l.invalidUnicodeEscape()
-							}
-							hexDigits = append(hexDigits, hexDigit)
-						}
-					}
-					if rightBrace := l.next(); rightBrace != '}' {
-						return l.invalidUnicodeEscape()
-					}
-					if len(hexDigits) < 1 || len(hexDigits) > 8 {
-						return l.invalidUnicodeEscape()
-					}
-					codePointInt64, err := strconv.ParseInt(string(hexDigits), 16, 32)
-					if err != nil {
-						return l.invalidUnicodeEscape()
-					}
-					unsafeRune := rune(codePointInt64)
-					if !utf8.ValidRune(unsafeRune) {
-						return l.invalidUnicodeEscape()
-					}
-					runes = append(runes, unsafeRune)
-				default:
-					return l.invalidEscape()
+		delimiter++
+	}
+	// The opening "
+	l.next()
+	if !strings.HasPrefix(l.input[l.pos:], `""`) {
+		runes, interpolations, _, errFn = scanStringSwiftContent(l, delimiter, false, nil)
+		return
+	}
+
+	l.next()
+	l.next()
+	skipHorizontalSpaces(l)
+	if !skipNewline(l) {
+		return nil, nil, l.multilineStringContentNotOnNewLine
+	}
+
+	// The indentation to strip is the indentation of the closing delimiter
+	// so the content has to be scanned twice.
+	contentStart := l.pos
+	_, _, indentation, errFn := scanStringSwiftContent(l, delimiter, true, nil)
+	if errFn != nil {
+		return nil, nil, errFn
+	}
+	l.pos = contentStart
+	runes, interpolations, _, errFn = scanStringSwiftContent(l, delimiter, true, &indentation)
+	return
+}
+
+// scanStringSwiftContent scans the content of a Swift string literal
+// after the opening delimiter up to and including the closing delimiter.
+// In multi-line string literal, the content begins on a new line and
+// indentation is stripped from every line if it is not nil.
+// closingIndentation is the indentation of the closing delimiter.
+func scanStringSwiftContent(l *lexer, delimiter int, multiline bool, indentation *string) (runes []rune, interpolations []stringInterpolation, closingIndentation string, errFn func() stateFn) {
+	hashes := strings.Repeat("#", delimiter)
+	closing := `"` + hashes
+	if multiline {
+		closing = `"""` + hashes
+	}
+	escape := `\` + hashes
+
+	runes = []rune{}
+	interpolations = []stringInterpolation{}
+	atLineStart := multiline
+	// The newline is appended only if
+	// the next line is not the closing delimiter.
+	pendingNewline := false
+	flush := func() {
+		if pendingNewline {
+			runes = append(runes, '\n')
+			pendingNewline = false
+		}
+	}
+
+	for {
+		if atLineStart {
+			atLineStart = false
+			spaces := skipHorizontalSpaces(l)
+			if strings.HasPrefix(l.input[l.pos:], closing) {
+				l.pos += len(closing)
+				return runes, interpolations, spaces, nil
+			}
+			if indentation != nil {
+				blank := l.peek() == '\n' || l.peek() == '\r'
+				if strings.HasPrefix(spaces, *indentation) {
+					spaces = spaces[len(*indentation):]
+				} else if blank {
+					spaces = ""
+				} else {
+					return nil, nil, "", l.insufficientIndentation
 				}
-			default:
+			}
+			flush()
+			runes = append(runes, []rune(spaces)...)
+		}
+
+		r := l.next()
+		switch r {
+		case eof:
+			return nil, nil, "", l.unterminatedStringLiteral
+		case '\n', '\r':
+			if !multiline {
+				return nil, nil, "", l.unterminatedStringLiteral
+			}
+			l.backup()
+			skipNewline(l)
+			flush()
+			pendingNewline = true
+			atLineStart = true
+			continue
+		case '"':
+			if strings.HasPrefix(l.input[l.pos-1:], closing) {
+				if multiline {
+					return nil, nil, "", l.multilineStringClosingDelimiterNotOnNewLine
+				}
+				l.pos += len(closing) - 1
+				return runes, interpolations, "", nil
+			}
+			runes = append(runes, r)
+			continue
+		case '\\':
+			if !strings.HasPrefix(l.input[l.pos-1:], escape) {
 				runes = append(runes, r)
+				continue
+			}
+			l.pos += len(hashes)
+		default:
+			runes = append(runes, r)
+			continue
+		}
+
+		// Escape sequence
+		nextRune := l.next()
+		switch nextRune {
+		case eof:
+			return nil, nil, "", l.unterminatedStringLiteral
+		case '(':
+			exprStart := l.pos
+			if !skipStringInterpolation(l, multiline) {
+				return nil, nil, "", l.invalidStringInterpolation
+			}
+			expr := l.input[exprStart : l.pos-1]
+			interpolations = append(interpolations, stringInterpolation{
+				index:     len(runes),
+				specifier: formatSpecifierOfInterpolation(expr),
+			})
+		case '\\':
+			runes = append(runes, '\\')
+		case '0':
+			runes = append(runes, rune(0))
+		case 't':
+			runes = append(runes, '\t')
+		case 'r':
+			runes = append(runes, '\r')
+		case 'n':
+			runes = append(runes, '\n')
+		case '\'':
+			runes = append(runes, '\'')
+		case '"':
+			runes = append(runes, '"')
+		case 'u':
+			unsafeRune, ok := lexUnicodeEscapeSwift(l)
+			if !ok {
+				return nil, nil, "", l.invalidUnicodeEscape
+			}
+			runes = append(runes, unsafeRune)
+		default:
+			// Line continuation
+			if multiline && (isHorizontalSpace(nextRune) || nextRune == '\n' || nextRune == '\r') {
+				l.backup()
+				skipHorizontalSpaces(l)
+				if !skipNewline(l) {
+					return nil, nil, "", l.invalidEscape
+				}
+				atLineStart = true
+				continue
 			}
+			return nil, nil, "", l.invalidEscape
 		}
 	}
 }
 
+// lexUnicodeEscapeSwift lexes {XXXX} after \u.
+func lexUnicodeEscapeSwift(l *lexer) (rune, bool) {
+	leftBrace := l.next()
+	if leftBrace != '{' {
+		return 0, false
+	}
+	hexDigits := []rune{}
+Loop:
+	for i := 0; i < 8; i++ {
+		hexDigit := l.next()
+		switch hexDigit {
+		case '}':
+			l.backup()
+			break Loop
+		default:
+			if !isHex(hexDigit) {
+				return 0, false
+			}
+			hexDigits = append(hexDigits, hexDigit)
+		}
+	}
+	if rightBrace := l.next(); rightBrace != '}' {
+		return 0, false
+	}
+	if len(hexDigits) < 1 || len(hexDigits) > 8 {
+		return 0, false
+	}
+	codePointInt64, err := strconv.ParseInt(string(hexDigits), 16, 32)
+	if err != nil {
+		return 0, false
+	}
+	unsafeRune := rune(codePointInt64)
+	if !utf8.ValidRune(unsafeRune) {
+		return 0, false
+	}
+	return unsafeRune, true
+}
+
 func lexStringObjc(state stateFn) stateFn {
 	// Based on C99 spec
 	return func(l *lexer) stateFn {
@@ -739,6 +1027,10 @@ func lexBareString(state stateFn) stateFn {
 
 func lexASCIIPlist(l *lexer) stateFn {
 	for {
+		// project.pbxproj starts with the line comment `// !$*UTF8*$!`
+		if strings.HasPrefix(l.input[l.pos:], "//") {
+			return lexLineComment(lexASCIIPlist)
+		}
 		if strings.HasPrefix(l.input[l.pos:], "/*") {
 			return lexComment(lexASCIIPlist)
 		}
@@ -797,13 +1089,25 @@ func lexIdentifier(state stateFn) stateFn {
 
 func lexRoutineCall(l *lexer) stateFn {
 	for {
+		if strings.HasPrefix(l.input[l.pos:], "//") {
+			return lexLineComment(lexRoutineCall)
+		}
+		if strings.HasPrefix(l.input[l.pos:], "/*") {
+			return l.syntax.lexBlockComment(lexRoutineCall)
+		}
 		r := l.next()
 		switch r {
 		case eof:
 			return l.eof()
 		case '"':
 			l.backup()
-			return l.lexString(lexRoutineCall)
+			return l.syntax.lexString(lexRoutineCall)
+		case '#':
+			if l.syntax.extendedDelimiter && isSwiftStringStart(l.input[l.pos-1:]) {
+				l.backup()
+				return l.syntax.lexString(lexRoutineCall)
+			}
+			l.ignore()
 		case '@':
 			l.emit(itemAtSign)
 		case '(':
@@ -377,6 +377,16 @@ func lexOneASCIIPlistString(l *lexer) stateFn {
 	}
 }
 
+func TestLexerNextItemAfterEOF(t *testing.T) {
+	l := newLexer("", "a.strings", lexASCIIPlist)
+	// The parser can read past EOF, e.g. when it expects EOF again.
+	for i := 0; i < 3; i++ {
+		if item := l.nextItem(); item.Type != itemEOF {
+			t.Errorf("%v: %v\n", i, item)
+		}
+	}
+}
+
 func TestLexStringSwift(t *testing.T) {
 	cases := []struct {
 		input    string
@@ -394,6 +404,25 @@ func TestLexStringSwift(t *testing.T) {
 		{`"\u{000000a}"`, "\n"},
 		{`"\u{0000000a}"`, "\n"},
 		{`"\u{0000000a}a"`, "\na"},
+
+		// extended delimiter
+		{`#"a\nb"#`, `a\nb`},
+		{`#"a\#nb"#`, "a\nb"},
+		{`#"a "b" c"#`, `a "b" c`},
+		{`##"a"#b"##`, `a"#b`},
+		{`#"\(a)"#`, `\(a)`},
+
+		// multi-line
+		{"\"\"\"\n  a\n   b\n  \"\"\"", "a\n b"},
+		{"\"\"\"\n\"\"\"", ""},
+		{"\"\"\"\n\n\"\"\"", ""},
+		{"\"\"\"  \n  a\n\n  b\n  \"\"\"", "a\n\nb"},
+		{"\"\"\"\n  a \\\n  b\n  \"\"\"", "a b"},
+		{"\"\"\"\n  a \\  \n  b\n  \"\"\"", "a b"},
+		{"\"\"\"\n  \"a\" \"\"\n  \"\"\"", `"a" ""`},
+		{"\"\"\"\r\n  a\r\n  b\r\n  \"\"\"", "a\nb"},
+		{"\"\"\"\n  a\\n\n  \"\"\"", "a\n"},
+		{"#\"\"\"\n  a\\n\"\"\"\n  \"\"\"#", `a\n"""`},
 	}
 	for _, c := range cases {
 		l := newLexer(c.input, "", lexOneSwiftString)
@@ -411,25 +440,36 @@ func TestLexStringSwift(t *testing.T) {
 
 func TestLexStringIntepolation(t *testing.T) {
 	cases := []struct {
-		input string
+		input    string
+		expected string
 	}{
-		{`"\(1)"`},
-		{`"\( 1 )"`},
-		{`"\( "1" )"`},
-		{`"\( "\(1)" )"`},
-		{`"\( "\( 1 )" )"`},
-		{`"\( "\(((( 1 ))))" )"`},
-		{`"\( "\("" + "")" )"`},
+		{`"\(1)"`, "%@"},
+		{`"\( 1 )"`, "%@"},
+		{`"\( "1" )"`, "%@"},
+		{`"\( "\(1)" )"`, "%@"},
+		{`"\( "\( 1 )" )"`, "%@"},
+		{`"\( "\(((( 1 ))))" )"`, "%@"},
+		{`"\( "\("" + "")" )"`, "%@"},
+		{`"a \(1) b \(2)"`, "a %@ b %@"},
+		{`"100% \(1)"`, "100%% %@"},
+		{`"\(d, specifier: "%.2f") km"`, "%.2f km"},
+		{`#"\#(a) \(b)"#`, `%@ \(b)`},
+		{`"\(#"a)"#)"`, "%@"},
+		{"\"\\(\"\"\"\n\"\"\")\"", "%@"},
+		{"\"\"\"\n  \\(a +\n  b)\n  \"\"\"", "%@"},
 	}
 	for _, c := range cases {
 		l := newLexer(c.input, "", lexOneSwiftString)
 		lexItems := drainLexer(&l)
-		if len(lexItems) != 1 {
+		if len(lexItems) != 2 {
 			t.Fail()
 		} else {
-			if lexItems[0].Type != itemEOF {
+			if lexItems[0].Type != itemInterpolatedString {
 				t.Fail()
 			}
+			if lexItems[0].Value != c.expected {
+				t.Errorf("%v\n", lexItems[0].Value)
+			}
 		}
 	}
 }
@@ -450,6 +490,16 @@ func TestLexStringSwiftInvalid(t *testing.T) {
 		{`"\u{}"`, ":1:1: invalid unicode escape"},
 		{`"\u{123456789}"`, ":1:1: invalid unicode escape"},
 		{`"\u{110000}"`, ":1:1: invalid unicode escape"},
+		{`"\(a`, ":1:1: invalid string interpolation"},
+		{`"\(a
+		)"`, ":1:1: invalid string interpolation"},
+		{`#"a"`, ":1:1: unterminated string literal"},
+		{`#"\#b"#`, ":1:1: invalid escape"},
+		{`"""a"""`, ":1:1: multi-line string literal content must begin on a new line"},
+		{"\"\"\"\n  a\"\"\"", ":1:1: multi-line string literal closing delimiter must begin on a new line"},
+		{"\"\"\"\na\n  \"\"\"", ":1:1: insufficient indentation of line in multi-line string literal"},
+		{"\"\"\"\n  a", ":1:1: unterminated string literal"},
+		{"\"\"\"\n  a \\ b\n  \"\"\"", ":1:1: invalid escape"},
 	}
 	for _, c := range cases {
 		l := newLexer(c.input, "", lexOneSwiftString)
@@ -654,3 +704,83 @@ func TestLexStringASCIIPlistInvalid(t *testing.T) {
 		}
 	}
 }
+
+func TestLexRoutineCallComment(t *testing.T) {
+	cases := []struct {
+		input           string
+		lexBlockComment func(stateFn) stateFn
+		expected        []itemType
+		values          []string
+	}{
+		{
+			"// a\nb",
+			lexNestedComment,
+			[]itemType{itemComment, itemSpaces, itemIdentifier, itemEOF},
+			[]string{" a", "\n", "b", ""},
+		},
+		{
+			"/* a /* b */ c */d",
+			lexNestedComment,
+			[]itemType{itemComment, itemIdentifier, itemEOF},
+			[]string{" a /* b */ c ", "d", ""},
+		},
+		{
+			"/* a /* b */ c */d",
+			lexComment,
+			[]itemType{itemComment, itemSpaces, itemIdentifier, itemSpaces, itemIdentifier, itemEOF},
+			[]string{" a /* b ", " ", "c", " ", "d", ""},
+		},
+		{
+			`"// a"`,
+			lexNestedComment,
+			[]itemType{itemString, itemEOF},
+			[]string{"// a", ""},
+		},
+	}
+	for _, c := range cases {
+		sourceSyntax := swiftSyntax
+		sourceSyntax.lexBlockComment = c.lexBlockComment
+		l := newLexerWithSyntax(c.input, "", sourceSyntax, lexRoutineCall)
+		lexItems := drainLexer(&l)
+		actualTypes := []itemType{}
+		actualValues := []string{}
+		for _, item := range lexItems {
+			actualTypes = append(actualTypes, item.Type)
+			actualValues = append(actualValues, item.Value)
+		}
+		if !reflect.DeepEqual(actualTypes, c.expected) {
+			t.Errorf("%v\n", actualTypes)
+		}
+		if !reflect.DeepEqual(actualValues, c.values) {
+			t.Errorf("%q\n", actualValues)
+		}
+	}
+
+	l := newLexerWithSyntax("/* /* */", "", swiftSyntax, lexRoutineCall)
+	lexItems := drainLexer(&l)
+	if len(lexItems) != 1 || lexItems[0].Type != itemError {
+		t.Fail()
+	}
+}
+
+func TestLexUnterminatedComment(t *testing.T) {
+	cases := []struct {
+		input           string
+		lexBlockComment func(stateFn) stateFn
+		msg             string
+	}{
+		{"a\n  /* b", lexNestedComment, "a.swift:2:3: unterminated comment"},
+		{"a\n  /* b /* c */", lexNestedComment, "a.swift:2:3: unterminated comment"},
+		{"a\n  /* b", lexComment, "a.swift:2:3: unterminated comment"},
+	}
+	for _, c := range cases {
+		sourceSyntax := swiftSyntax
+		sourceSyntax.lexBlockComment = c.lexBlockComment
+		l := newLexerWithSyntax(c.input, "a.swift", sourceSyntax, lexRoutineCall)
+		lexItems := drainLexer(&l)
+		last := lexItems[len(lexItems)-1]
+		if last.Type != itemError || last.Err.Error() != c.msg {
+			t.Errorf("%v\n", last.Err)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+func isLocalizationValue(token lexItem) bool {
+	return token.Type == itemString || token.Type == itemInterpolatedString
+}
+
+// parseLocalizationValue parses a Swift string literal
+// which may contain interpolations.
+// The interpolations become format specifiers.
+func (p *routineCallParser) parseLocalizationValue() string {
+	token := p.nextNonSpace()
+	if !isLocalizationValue(token) {
+		p.unexpected(token)
+	}
+	return token.Value
+}
+
+// parseStringLocalized parses
+// String(localized: key, defaultValue: value, table: table, bundle: bundle, locale: locale, comment: comment)
+// It returns false if the call is not String(localized:) with a literal key.
+func (p *routineCallParser) parseStringLocalized() (rc routineCall, ok bool) {
+	if token := p.nextNonSpace(); token.Type != itemParenLeft {
+		p.backup()
+		return rc, false
+	}
+	if token := p.nextNonSpace(); token.Type != itemIdentifier || token.Value != "localized" {
+		p.backup()
+		return rc, false
+	}
+	p.expect(itemColon)
+	// String(localized: resource) does not have a literal key.
+	if token := p.nextNonSpace(); !isLocalizationValue(token) {
+		p.backup()
+		return rc, false
+	}
+	p.backup()
+	rc.key = p.parseLocalizationValue()
+	p.parseLocalizedArguments(&rc)
+	return rc, true
+}
+
+// parseLocalizedStringResource parses
+// LocalizedStringResource(key, defaultValue: value, table: table, locale: locale, bundle: bundle, comment: comment)
+// It returns false if the key is not a literal.
+func (p *routineCallParser) parseLocalizedStringResource() (rc routineCall, ok bool) {
+	if token := p.nextNonSpace(); token.Type != itemParenLeft {
+		p.backup()
+		return rc, false
+	}
+	if token := p.nextNonSpace(); token.Type != itemString {
+		p.backup()
+		return rc, false
+	}
+	p.backup()
+	rc.key = p.parseString()
+	p.parseLocalizedArguments(&rc)
+	return rc, true
+}
+
+// parseLocalizedArguments parses the labeled arguments after the key
+// until the right paren.
+func (p *routineCallParser) parseLocalizedArguments(rc *routineCall) {
+	for {
+		token := p.nextNonSpace()
+		if token.Type == itemParenRight {
+			return
+		}
+		if token.Type != itemComma {
+			p.unexpected(token)
+		}
+		label := p.expect(itemIdentifier)
+		p.expect(itemColon)
+		switch label.Value {
+		case "defaultValue":
+			rc.value = p.parseLocalizationValue()
+		case "table":
+			rc.table = p.parseString()
+		case "comment":
+			rc.comment = p.parseString()
+		case "bundle", "locale":
+			p.skipExpression()
+		default:
+			p.unexpected(label)
+		}
+	}
+}
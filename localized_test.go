@@ -0,0 +1,75 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseStringLocalized(t *testing.T) {
+	routineName := "NSLocalizedString"
+	input := `
+let a: String = String(localized: "key1", defaultValue: "value", table: "Settings", bundle: .main, locale: .current, comment: "comment")
+let b = String(localized: "key2")
+let c = String(localized: resource)
+let d = String(describing: a)
+let e = LocalizedStringResource("key3", defaultValue: "value", comment: "comment")
+let f = [String]()
+let g = String(localized: "Hello, \(name)")
+`
+	expected := routineCallSlice{
+		routineCall{
+			filepath:  ".swift",
+			startLine: 2,
+			startCol:  17,
+			endLine:   2,
+			endCol:    137,
+			key:       "key1",
+			comment:   "comment",
+			table:     "Settings",
+			value:     "value",
+		},
+		routineCall{
+			filepath:  ".swift",
+			startLine: 3,
+			startCol:  9,
+			endLine:   3,
+			endCol:    34,
+			key:       "key2",
+		},
+		routineCall{
+			filepath:  ".swift",
+			startLine: 6,
+			startCol:  9,
+			endLine:   6,
+			endCol:    83,
+			key:       "key3",
+			comment:   "comment",
+			value:     "value",
+		},
+		routineCall{
+			filepath:  ".swift",
+			startLine: 8,
+			startCol:  9,
+			endLine:   8,
+			endCol:    44,
+			key:       "Hello, %@",
+		},
+	}
+	actual, err := parseRoutineCalls(input, routineName, ".swift", false)
+	if err != nil {
+		t.Errorf("%v\n", err)
+	} else if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("%v\n", actual)
+	}
+
+	// Objective-C does not have these APIs.
+	actual, err = parseRoutineCalls(`String(localized: "key")`, routineName, ".m", false)
+	if err != nil || len(actual) != 0 {
+		t.Fail()
+	}
+
+	_, err = parseRoutineCalls(`String(localized: "key", foo: "")`, routineName, ".swift", false)
+	if err == nil || err.Error() != ".swift:1:26: unexpected token `<ident>`" {
+		t.Errorf("%v\n", err)
+	}
+}
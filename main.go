@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"os"
 	"regexp"
+
+	"github.com/iawaknahc/gogenstrings/errors"
 )
 
 func parseOptionalRegexp(pattern string) (*regexp.Regexp, error) {
@@ -18,31 +20,289 @@ func parseOptionalRegexp(pattern string) (*regexp.Regexp, error) {
 	return re, nil
 }
 
+const (
+	formatText  = "text"
+	formatJSON  = "json"
+	formatSARIF = "sarif"
+)
+
+const (
+	toolName       = "gogenstrings"
+	informationURI = "https://github.com/iawaknahc/gogenstrings"
+)
+
+// report prints err in format.
+// Text goes to stderr. JSON and SARIF go to stdout
+// and are printed even if err is nil.
+func report(format string, err error) {
+	switch format {
+	case formatJSON:
+		if err := errors.WriteJSON(os.Stdout, errors.ToDiagnostics(err)); err != nil {
+			exitWithError(err)
+		}
+	case formatSARIF:
+		if err := errors.WriteSARIF(os.Stdout, errors.ToDiagnostics(err), toolName, informationURI); err != nil {
+			exitWithError(err)
+		}
+	default:
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+		}
+	}
+}
+
+// contextFlags are the flags shared by every command.
+type contextFlags struct {
+	root      *string
+	devlang   *string
+	routine   *string
+	exclude   *string
+	infoPlist *string
+	swiftUI   *bool
+	ib        *bool
+	settings  *string
+	xcodeproj *string
+	target    *string
+	encoding  *string
+	format    *string
+}
+
+func registerContextFlags(fs *flag.FlagSet) contextFlags {
+	return contextFlags{
+		root:      fs.String("root", ".", "the root path to the target"),
+		devlang:   fs.String("devlang", "", "the development language; derived from -infoplist, -xcodeproj and Package.swift if empty, otherwise en"),
+		routine:   fs.String("routine", "NSLocalizedString", "the routine name to extract"),
+		exclude:   fs.String("exclude", "", "the regexp to exclude"),
+		infoPlist: fs.String("infoplist", "", "the path to Info.plist to generate InfoPlist.strings"),
+		swiftUI:   fs.Bool("swiftui", false, "extract SwiftUI views and LocalizedStringKey"),
+		ib:        fs.Bool("ib", false, "extract storyboards and XIBs in Base.lproj into <Name>.strings"),
+		settings:  fs.String("settings", "", "the path to Settings.bundle to generate Root.strings"),
+		xcodeproj: fs.String("xcodeproj", "", "the path to .xcodeproj to take the source files and the known regions from"),
+		target:    fs.String("target", "", "the target in -xcodeproj; required if there are multiple targets"),
+		encoding:  fs.String("encoding", encodingPreserve, "the encoding of written files: preserve, utf-8, utf-8-bom, utf-16le or utf-16be"),
+		format:    fs.String("format", formatText, "the format of diagnostics: text, json or sarif"),
+	}
+}
+
+func (f contextFlags) validateFormat() error {
+	switch *f.format {
+	case formatText, formatJSON, formatSARIF:
+		return nil
+	}
+	return fmt.Errorf("unknown format `%v`", *f.format)
+}
+
+func (f contextFlags) newContext() (genstringsContext, error) {
+	if err := f.validateFormat(); err != nil {
+		return genstringsContext{}, err
+	}
+
+	excludeRe, err := parseOptionalRegexp(*f.exclude)
+	if err != nil {
+		return genstringsContext{}, err
+	}
+
+	var forceEncoding encoding
+	if *f.encoding != encodingPreserve {
+		forceEncoding, err = parseEncoding(*f.encoding)
+		if err != nil {
+			return genstringsContext{}, err
+		}
+	}
+
+	ctx := newGenstringsContext(genstringsOptions{
+		rootPath:           *f.root,
+		devlang:            *f.devlang,
+		routineName:        *f.routine,
+		infoPlistPath:      *f.infoPlist,
+		excludeRegexp:      excludeRe,
+		swiftUI:            *f.swiftUI,
+		interfaceBuilder:   *f.ib,
+		settingsBundlePath: *f.settings,
+		xcodeprojPath:      *f.xcodeproj,
+		xcodeprojTarget:    *f.target,
+		forceEncoding:      forceEncoding,
+	})
+	return ctx, nil
+}
+
+func exitWithError(err error) {
+	fmt.Fprintf(os.Stderr, "%v\n", err)
+	os.Exit(1)
+}
+
 func main() {
-	rootPtr := flag.String("root", ".", "the root path to the target")
-	devLangPtr := flag.String("devlang", "en", "the development language")
-	routinePtr := flag.String("routine", "NSLocalizedString", "the routine name to extract")
-	excludePtr := flag.String("exclude", "", "the regexp to exclude")
-	flag.Parse()
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "export-xliff":
+			exportXLIFFMain(os.Args[2:])
+			return
+		case "import-xliff":
+			importXLIFFMain(os.Args[2:])
+			return
+		case "convert":
+			convertMain(os.Args[2:])
+			return
+		}
+	}
+	genstringsMain(os.Args[1:])
+}
+
+func genstringsMain(args []string) {
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	f := registerContextFlags(fs)
+	checkPtr := fs.Bool("check", false, "exit non-zero if any file is out of date without writing anything")
+	dryRunPtr := fs.Bool("dry-run", false, "print the unified diff of the changes without writing anything")
+	auditPtr := fs.Bool("audit", false, "check the .strings and .stringsdict of the build product at root, e.g. an .app, without writing anything")
+	packagePtr := fs.Bool("package", false, "treat root as a Swift package and process every target with its own lprojs")
+	fs.Parse(args)
+
+	ctx, err := f.newContext()
+	if err != nil {
+		exitWithError(err)
+	}
+	format := *f.format
+	// Both the diff and the diagnostics would be printed to stdout.
+	if *dryRunPtr && format != formatText {
+		exitWithError(fmt.Errorf("-dry-run cannot be used with -format %v", format))
+	}
+	// Every target of a package is found by Package.swift,
+	// so these flags cannot apply to all of them.
+	if *packagePtr {
+		if *f.xcodeproj != "" {
+			exitWithError(fmt.Errorf("-package cannot be used with -xcodeproj"))
+		}
+		if *f.infoPlist != "" {
+			exitWithError(fmt.Errorf("-package cannot be used with -infoplist"))
+		}
+		if *f.settings != "" {
+			exitWithError(fmt.Errorf("-package cannot be used with -settings"))
+		}
+	}
+
+	if *auditPtr {
+		if *packagePtr {
+			exitWithError(fmt.Errorf("-audit cannot be used with -package"))
+		}
+		err := ctx.audit()
+		report(format, err)
+		if err != nil {
+			os.Exit(1)
+		}
+		return
+	}
+
+	contexts := []genstringsContext{ctx}
+	if *packagePtr {
+		contexts, err = ctx.packageContexts()
+		if err != nil {
+			report(format, err)
+			os.Exit(1)
+		}
+	}
+
+	// Targets of a package are independent
+	// so that errors of every target are reported.
+	diagnostics := errors.Diagnostics{}
+
+	if *checkPtr {
+		for _, ctx := range contexts {
+			outdated, err := ctx.check()
+			diagnostics.Add(err)
+			for _, targetPath := range outdated {
+				diagnostics.Add(errors.File(targetPath, "file is out of date").WithRule(ruleOutdatedFile))
+			}
+		}
+		err := diagnostics.Err()
+		report(format, err)
+		if err != nil {
+			os.Exit(1)
+		}
+		return
+	}
 
-	excludeRe, err := parseOptionalRegexp(*excludePtr)
+	if *dryRunPtr {
+		for _, ctx := range contexts {
+			// The diff is printed even with bad translations.
+			diff, err := ctx.dryRun()
+			fmt.Print(diff)
+			diagnostics.Add(err)
+		}
+		err := diagnostics.Err()
+		report(format, err)
+		if err != nil {
+			os.Exit(1)
+		}
+		return
+	}
+
+	for _, ctx := range contexts {
+		diagnostics.Add(ctx.genstrings())
+	}
+	err = diagnostics.Err()
+	report(format, err)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
 		os.Exit(1)
 	}
+}
 
-	rootPath := *rootPtr
-	devlang := *devLangPtr
-	routineName := *routinePtr
+func exportXLIFFMain(args []string) {
+	fs := flag.NewFlagSet(os.Args[0]+" export-xliff", flag.ExitOnError)
+	f := registerContextFlags(fs)
+	outPtr := fs.String("out", ".", "the directory to write <lang>.xliff")
+	fs.Parse(args)
 
-	ctx := newGenstringsContext(
-		rootPath,
-		devlang,
-		routineName,
-		excludeRe,
-	)
-	if err := ctx.genstrings(); err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
+	ctx, err := f.newContext()
+	if err != nil {
+		exitWithError(err)
+	}
+	err = ctx.exportXLIFF(*outPtr)
+	report(*f.format, err)
+	if err != nil {
+		os.Exit(1)
+	}
+}
+
+func importXLIFFMain(args []string) {
+	fs := flag.NewFlagSet(os.Args[0]+" import-xliff", flag.ExitOnError)
+	f := registerContextFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %v [flags] file.xliff...\n", fs.Name())
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	ctx, err := f.newContext()
+	if err != nil {
+		exitWithError(err)
+	}
+	// Keys no longer exist are reported
+	// but do not fail the import.
+	obsolete, err := ctx.importXLIFF(fs.Args())
+	if err != nil {
+		diagnostics := errors.Diagnostics{}
+		diagnostics.Add(obsolete)
+		diagnostics.Add(err)
+		report(*f.format, diagnostics.Err())
+		os.Exit(1)
+	}
+	report(*f.format, obsolete)
+}
+
+func convertMain(args []string) {
+	fs := flag.NewFlagSet(os.Args[0]+" convert", flag.ExitOnError)
+	f := registerContextFlags(fs)
+	toPtr := fs.String("to", convertToStringCatalog, "the format to convert to: xcstrings or strings")
+	removePtr := fs.Bool("remove", false, "remove the converted files after every file is written")
+	fs.Parse(args)
+
+	ctx, err := f.newContext()
+	if err != nil {
+		exitWithError(err)
+	}
+	err = ctx.convert(*toPtr, *removePtr)
+	report(*f.format, err)
+	if err != nil {
 		os.Exit(1)
 	}
 }
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/iawaknahc/gogenstrings/errors"
+	"github.com/iawaknahc/gogenstrings/linecol"
+)
+
+// packageTargetRe matches the targets which can have resources.
+var packageTargetRe = regexp.MustCompile(`^\.(?:target|executableTarget)\s*\(`)
+
+var packageStringArgumentRe = regexp.MustCompile(`^(name|path)\s*:\s*"([^"]*)"$`)
+
+// swiftPackage is what Package.swift tells about localization.
+type swiftPackage struct {
+	defaultLocalization string
+	targets             []swiftPackageTarget
+}
+
+type swiftPackageTarget struct {
+	name string
+	// path is relative to the package.
+	path string
+}
+
+// skipCommentOrString returns the index after
+// the comment or the string literal starting at src[i].
+// It returns i if there is none.
+func skipCommentOrString(src string, i int) int {
+	switch {
+	case strings.HasPrefix(src[i:], "//"):
+		if end := strings.IndexByte(src[i:], '\n'); end >= 0 {
+			return i + end
+		}
+		return len(src)
+	case strings.HasPrefix(src[i:], "/*"):
+		// Block comments can be nested in Swift.
+		depth := 0
+		for j := i; j < len(src); j++ {
+			if strings.HasPrefix(src[j:], "/*") {
+				depth++
+				j++
+			} else if strings.HasPrefix(src[j:], "*/") {
+				depth--
+				j++
+				if depth <= 0 {
+					return j + 1
+				}
+			}
+		}
+		return len(src)
+	case src[i] == '"':
+		j := i + 1
+		for ; j < len(src) && src[j] != '"'; j++ {
+			if src[j] == '\\' {
+				j++
+			}
+		}
+		if j >= len(src) {
+			return len(src)
+		}
+		return j + 1
+	}
+	return i
+}
+
+// splitArguments returns the arguments of the call
+// whose left paren is before src[start].
+// Comments are removed from the arguments.
+func splitArguments(src string, start int) ([]string, bool) {
+	args := []string{}
+	depth := 1
+	var arg strings.Builder
+	for i := start; i < len(src); i++ {
+		if j := skipCommentOrString(src, i); j > i {
+			if src[i] == '"' {
+				arg.WriteString(src[i:j])
+			} else {
+				arg.WriteByte(' ')
+			}
+			i = j - 1
+			continue
+		}
+		switch src[i] {
+		case '(', '[':
+			depth++
+		case ')', ']':
+			depth--
+			if depth <= 0 {
+				args = append(args, strings.TrimSpace(arg.String()))
+				return args, true
+			}
+		case ',':
+			if depth == 1 {
+				args = append(args, strings.TrimSpace(arg.String()))
+				arg.Reset()
+				continue
+			}
+		}
+		arg.WriteByte(src[i])
+	}
+	return nil, false
+}
+
+// parsePackageSwift parses the targets of Package.swift.
+// It does not evaluate Swift so that only literal names
+// and paths are understood.
+func parsePackageSwift(src, filepath string) (swiftPackage, error) {
+	pkg := swiftPackage{}
+	if source, ok := parseDefaultLocalization(src, filepath); ok {
+		pkg.defaultLocalization = source.devlang
+	}
+	lineColer := linecol.NewLineColer(src)
+	for i := 0; i < len(src); {
+		// Targets in comments and string literals are ignored.
+		if j := skipCommentOrString(src, i); j > i {
+			i = j
+			continue
+		}
+		loc := packageTargetRe.FindStringIndex(src[i:])
+		if loc == nil {
+			i++
+			continue
+		}
+		line, col := lineColer.LineCol(i)
+		i += loc[1]
+		args, ok := splitArguments(src, i)
+		if !ok {
+			return pkg, errors.FileLineCol(filepath, line, col, "unexpected EOF; expected )")
+		}
+		target := swiftPackageTarget{}
+		for _, arg := range args {
+			match := packageStringArgumentRe.FindStringSubmatch(arg)
+			if match == nil {
+				continue
+			}
+			switch match[1] {
+			case "name":
+				target.name = match[2]
+			case "path":
+				target.path = match[2]
+			}
+		}
+		if target.name == "" {
+			return pkg, errors.FileLineCol(filepath, line, col, "target has no name")
+		}
+		if target.path == "" {
+			target.path = "Sources/" + target.name
+		}
+		pkg.targets = append(pkg.targets, target)
+	}
+	return pkg, nil
+}
+
+// packageContexts returns a context for every target
+// in Package.swift at rootPath.
+// Targets without lproj nor String Catalog are not localized
+// and are skipped.
+func (p *genstringsContext) packageContexts() ([]genstringsContext, error) {
+	packageSwiftPath := filepath.Join(p.rootPath, packageSwiftBasename)
+	content, err := readFile(packageSwiftPath)
+	if err != nil {
+		return nil, err
+	}
+	pkg, err := parsePackageSwift(content, packageSwiftPath)
+	if err != nil {
+		return nil, err
+	}
+
+	devlang := p.devlang
+	if devlang == "" {
+		devlang = pkg.defaultLocalization
+	}
+	if devlang == "" {
+		devlang = defaultDevlang
+	}
+
+	out := []genstringsContext{}
+	for _, target := range pkg.targets {
+		targetPath := filepath.Join(p.rootPath, target.path)
+		info, err := os.Stat(targetPath)
+		if err != nil || !info.IsDir() {
+			return nil, errors.File(targetPath, fmt.Sprintf("directory of target `%v` not found", target.name))
+		}
+		lprojs, err := findLprojs(targetPath)
+		if err != nil {
+			return nil, err
+		}
+		catalogPaths, err := findStringCatalogs(targetPath, p.excludeRegexp)
+		if err != nil {
+			return nil, err
+		}
+		if len(lprojs) <= 0 && len(catalogPaths) <= 0 {
+			continue
+		}
+		// Every target has its own bundle, i.e. Bundle.module.
+		ctx := newGenstringsContext(genstringsOptions{
+			rootPath:         targetPath,
+			devlang:          devlang,
+			routineName:      p.routineName,
+			excludeRegexp:    p.excludeRegexp,
+			swiftUI:          p.swiftUI,
+			interfaceBuilder: p.interfaceBuilder,
+			forceEncoding:    p.forceEncoding,
+		})
+		out = append(out, ctx)
+	}
+	return out, nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testPackageSwift = `// swift-tools-version:5.3
+import PackageDescription
+
+let package = Package(
+    name: "Foo",
+    defaultLocalization: "en",
+    products: [
+        .library(name: "Foo", targets: ["Foo"]),
+    ],
+    targets: [
+        // .target(name: "Commented"),
+        .target(
+            name: "Foo",
+            dependencies: [.product(name: "Dep", package: "dep")],
+            resources: [.process("Resources")]
+        ),
+        .target(name: "Bar", path: "Custom/Bar"),
+        .executableTarget(name: "Baz"),
+        .testTarget(name: "FooTests", dependencies: ["Foo"]),
+    ]
+)
+`
+
+func TestParsePackageSwift(t *testing.T) {
+	pkg, err := parsePackageSwift(testPackageSwift, "Package.swift")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if pkg.defaultLocalization != "en" {
+		t.Errorf("%v\n", pkg.defaultLocalization)
+	}
+	expected := []swiftPackageTarget{
+		{name: "Foo", path: "Sources/Foo"},
+		{name: "Bar", path: "Custom/Bar"},
+		{name: "Baz", path: "Sources/Baz"},
+	}
+	if !reflect.DeepEqual(pkg.targets, expected) {
+		t.Errorf("%v\n", pkg.targets)
+	}
+
+	// Comments and strings are skipped together.
+	pkg, err = parsePackageSwift(`let package = Package(
+    dependencies: [.package(url: "https://example.com/dep.git", from: "1.0.0")], .target(name: "A"),
+    targets: [
+        /* .target(name: "Block"),
+           /* nested */ .target(name: "Nested"), */
+        .target(name: "B" /* , path: "Commented" */),
+        .target(name: "C", exclude: ["//", ".target(name: \"String\")"]),
+    ]
+)`, "Package.swift")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	expected = []swiftPackageTarget{
+		{name: "A", path: "Sources/A"},
+		{name: "B", path: "Sources/B"},
+		{name: "C", path: "Sources/C"},
+	}
+	if !reflect.DeepEqual(pkg.targets, expected) {
+		t.Errorf("%v\n", pkg.targets)
+	}
+
+	_, err = parsePackageSwift("let package = Package(targets: [\n.target(path: \"a\")])", "Package.swift")
+	if err == nil || err.Error() != "Package.swift:2:1: target has no name" {
+		t.Errorf("%v\n", err)
+	}
+	_, err = parsePackageSwift("let package = Package(targets: [.target(name: \"a\"", "Package.swift")
+	if err == nil || err.Error() != "Package.swift:1:33: unexpected EOF; expected )" {
+		t.Errorf("%v\n", err)
+	}
+}
+
+func TestPackageContexts(t *testing.T) {
+	root, err := ioutil.TempDir("", "gogenstrings")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	defer os.RemoveAll(root)
+
+	writeFiles(t, root, map[string]string{
+		"Package.swift":         testPackageSwift,
+		"Sources/Foo/Foo.swift": `Text("foo", bundle: .module)`,
+		"Sources/Foo/Resources/en.lproj/Localizable.strings": "",
+		"Custom/Bar/Bar.swift":                               `NSLocalizedString("bar", bundle: .module, comment: "")`,
+		"Custom/Bar/Resources/en.lproj/Localizable.strings":  "",
+		"Sources/Baz/main.swift":                             `NSLocalizedString("baz", comment: "")`,
+	})
+
+	ctx := newGenstringsContext(genstringsOptions{
+		rootPath:    root,
+		routineName: "NSLocalizedString",
+		swiftUI:     true,
+	})
+	contexts, err := ctx.packageContexts()
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	// Baz is not localized.
+	if len(contexts) != 2 {
+		t.Fatalf("%v\n", len(contexts))
+	}
+	for _, ctx := range contexts {
+		if err := ctx.genstrings(); err != nil {
+			t.Fatalf("%v\n", err)
+		}
+	}
+
+	expected := map[string]string{
+		"Sources/Foo/Resources/en.lproj/Localizable.strings": "/* No comment provided by engineer. */\n\"foo\" = \"foo\";\n\n",
+		"Custom/Bar/Resources/en.lproj/Localizable.strings":  "/* No comment provided by engineer. */\n\"bar\" = \"bar\";\n\n",
+	}
+	for name, content := range expected {
+		if actual, err := readFile(filepath.Join(root, name)); err != nil || actual != content {
+			t.Errorf("%v: %q %v\n", name, actual, err)
+		}
+	}
+}
@@ -0,0 +1,361 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/iawaknahc/gogenstrings/errors"
+)
+
+const (
+	pbxprojBasename = "project.pbxproj"
+	baseRegion      = "Base"
+)
+
+// pbxproj is project.pbxproj.
+type pbxproj struct {
+	filepath string
+	// srcRoot is the directory containing .xcodeproj.
+	srcRoot    string
+	objects    map[string]map[string]interface{}
+	rootObject string
+	// parents is the group of every file and group.
+	parents map[string]string
+}
+
+func parsePBXProj(src, filepath, srcRoot string) (pbxproj, error) {
+	p := pbxproj{
+		filepath: filepath,
+		srcRoot:  srcRoot,
+		objects:  make(map[string]map[string]interface{}),
+		parents:  make(map[string]string),
+	}
+	node, err := parseASCIIPlist(src, filepath)
+	if err != nil {
+		return p, err
+	}
+	root, ok := node.Flatten().(map[string]interface{})
+	if !ok {
+		return p, errors.FileLineCol(filepath, node.Line, node.Col, "not in project.pbxproj format")
+	}
+	objects, _ := root["objects"].(map[string]interface{})
+	for id, object := range objects {
+		if m, ok := object.(map[string]interface{}); ok {
+			p.objects[id] = m
+		}
+	}
+	p.rootObject, _ = root["rootObject"].(string)
+	if p.object(p.rootObject) == nil {
+		return p, errors.File(filepath, "rootObject not found")
+	}
+	for id, object := range p.objects {
+		for _, child := range stringsOf(object, "children") {
+			p.parents[child] = id
+		}
+	}
+	return p, nil
+}
+
+func stringOf(object map[string]interface{}, key string) string {
+	s, _ := object[key].(string)
+	return s
+}
+
+func stringsOf(object map[string]interface{}, key string) []string {
+	out := []string{}
+	array, _ := object[key].([]interface{})
+	for _, value := range array {
+		if s, ok := value.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func (p pbxproj) object(id string) map[string]interface{} {
+	return p.objects[id]
+}
+
+func (p pbxproj) project() map[string]interface{} {
+	return p.object(p.rootObject)
+}
+
+func (p pbxproj) developmentRegion() string {
+	return stringOf(p.project(), "developmentRegion")
+}
+
+// knownRegions returns the regions except Base.
+func (p pbxproj) knownRegions() []string {
+	out := []string{}
+	for _, region := range stringsOf(p.project(), "knownRegions") {
+		if region != baseRegion {
+			out = append(out, region)
+		}
+	}
+	return out
+}
+
+// target returns the target of the given name.
+// The name can be empty if there is only one target.
+func (p pbxproj) target(name string) (map[string]interface{}, error) {
+	names := []string{}
+	for _, id := range stringsOf(p.project(), "targets") {
+		target := p.object(id)
+		if target == nil {
+			continue
+		}
+		if stringOf(target, "name") == name {
+			return target, nil
+		}
+		names = append(names, stringOf(target, "name"))
+	}
+	if name == "" && len(names) == 1 {
+		return p.target(names[0])
+	}
+	sort.Strings(names)
+	if name == "" {
+		return nil, errors.File(p.filepath, fmt.Sprintf("specify one of the targets: %v", strings.Join(names, ", ")))
+	}
+	return nil, errors.File(p.filepath, fmt.Sprintf("target `%v` not found", name))
+}
+
+// buildPhaseFiles returns the file references
+// in the build phases of isa, e.g. PBXSourcesBuildPhase.
+func (p pbxproj) buildPhaseFiles(target map[string]interface{}, isa string) []string {
+	out := []string{}
+	for _, phaseID := range stringsOf(target, "buildPhases") {
+		phase := p.object(phaseID)
+		if stringOf(phase, "isa") != isa {
+			continue
+		}
+		for _, buildFileID := range stringsOf(phase, "files") {
+			if fileRef := stringOf(p.object(buildFileID), "fileRef"); fileRef != "" {
+				out = append(out, fileRef)
+			}
+		}
+	}
+	return out
+}
+
+// resolvePath returns the path of a file or a group.
+// Paths relative to build settings other than SOURCE_ROOT,
+// e.g. SDKROOT, cannot be resolved.
+func (p pbxproj) resolvePath(id string) (string, bool) {
+	object := p.object(id)
+	if object == nil {
+		return "", false
+	}
+	path := stringOf(object, "path")
+	switch stringOf(object, "sourceTree") {
+	case "<absolute>":
+		return path, true
+	case "SOURCE_ROOT":
+		return filepath.Join(p.srcRoot, path), true
+	case "<group>":
+		parent, ok := p.parents[id]
+		if !ok {
+			// The main group
+			projectDir := filepath.Join(p.srcRoot, stringOf(p.project(), "projectDirPath"))
+			return filepath.Join(projectDir, path), true
+		}
+		dir, ok := p.resolvePath(parent)
+		if !ok {
+			return "", false
+		}
+		return filepath.Join(dir, path), true
+	}
+	return "", false
+}
+
+// synchronizedGroupFiles returns the files in the synchronized groups
+// of target, i.e. PBXFileSystemSynchronizedRootGroup of objectVersion 77.
+// The membership exceptions of target are excluded.
+func (p pbxproj) synchronizedGroupFiles(target map[string]interface{}) ([]string, error) {
+	out := []string{}
+	for _, groupID := range stringsOf(target, "fileSystemSynchronizedGroups") {
+		group := p.object(groupID)
+		if stringOf(group, "isa") != "PBXFileSystemSynchronizedRootGroup" {
+			continue
+		}
+		dir, ok := p.resolvePath(groupID)
+		if !ok {
+			continue
+		}
+		exceptions := make(map[string]bool)
+		for _, exceptionID := range stringsOf(group, "exceptions") {
+			exception := p.object(exceptionID)
+			if stringOf(p.object(stringOf(exception, "target")), "name") != stringOf(target, "name") {
+				continue
+			}
+			for _, path := range stringsOf(exception, "membershipExceptions") {
+				exceptions[filepath.Join(dir, path)] = true
+			}
+		}
+		walkFn := func(fullpath string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if exceptions[fullpath] {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if info.Mode().IsRegular() {
+				out = append(out, fullpath)
+			}
+			return nil
+		}
+		if err := filepath.Walk(dir, walkFn); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// lprojParent returns the directory containing the lprojs
+// of the localized .strings in the resources of target.
+// Localizable.strings is preferred.
+// The synchronized files are used if there is no variant group.
+func (p pbxproj) lprojParent(target map[string]interface{}, synchronized []string) (string, bool) {
+	candidates := []string{}
+	for _, id := range p.buildPhaseFiles(target, "PBXResourcesBuildPhase") {
+		group := p.object(id)
+		if stringOf(group, "isa") != "PBXVariantGroup" {
+			continue
+		}
+		name := stringOf(group, "name")
+		if filepath.Ext(name) != dotStringsExt {
+			continue
+		}
+		for _, child := range stringsOf(group, "children") {
+			path, ok := p.resolvePath(child)
+			if !ok {
+				continue
+			}
+			lproj := filepath.Dir(path)
+			if filepath.Ext(lproj) != ".lproj" {
+				continue
+			}
+			if name == defaultTable+dotStringsExt {
+				return filepath.Dir(lproj), true
+			}
+			candidates = append(candidates, filepath.Dir(lproj))
+		}
+	}
+	for _, path := range synchronized {
+		lproj := filepath.Dir(path)
+		if filepath.Ext(path) != dotStringsExt || filepath.Ext(lproj) != ".lproj" {
+			continue
+		}
+		if filepath.Base(path) == defaultTable+dotStringsExt {
+			return filepath.Dir(lproj), true
+		}
+		candidates = append(candidates, filepath.Dir(lproj))
+	}
+	if len(candidates) <= 0 {
+		return "", false
+	}
+	return candidates[0], true
+}
+
+// interfaceBuilderFiles returns the storyboards and XIBs
+// in Base.lproj in the resources of target
+// and in the synchronized files.
+func (p pbxproj) interfaceBuilderFiles(target map[string]interface{}, synchronized []string) []string {
+	out := []string{}
+	for _, path := range synchronized {
+		if isInterfaceBuilderFile(path) && filepath.Base(filepath.Dir(path)) == baseLprojName {
+			out = append(out, path)
+		}
+	}
+	for _, id := range p.buildPhaseFiles(target, "PBXResourcesBuildPhase") {
+		group := p.object(id)
+		if stringOf(group, "isa") != "PBXVariantGroup" || !isInterfaceBuilderFile(stringOf(group, "name")) {
+			continue
+		}
+		for _, child := range stringsOf(group, "children") {
+			path, ok := p.resolvePath(child)
+			if ok && filepath.Base(filepath.Dir(path)) == baseLprojName {
+				out = append(out, path)
+			}
+		}
+	}
+	return out
+}
+
+func (p *genstringsContext) readPBXProj() (pbxproj, error) {
+	pbxprojPath := filepath.Join(p.xcodeprojPath, pbxprojBasename)
+	content, err := readFile(pbxprojPath)
+	if err != nil {
+		return pbxproj{}, err
+	}
+	return parsePBXProj(content, pbxprojPath, filepath.Dir(p.xcodeprojPath))
+}
+
+// findXcodeproj is like find but the source files are
+// those of the target and the lprojs are the known regions.
+func (p *genstringsContext) findXcodeproj() error {
+	if err := p.resolveDevlang(); err != nil {
+		return err
+	}
+	if err := p.findStringCatalogs(); err != nil {
+		return err
+	}
+
+	proj, err := p.readPBXProj()
+	if err != nil {
+		return err
+	}
+	target, err := proj.target(p.xcodeprojTarget)
+	if err != nil {
+		return err
+	}
+
+	synchronized, err := proj.synchronizedGroupFiles(target)
+	if err != nil {
+		return err
+	}
+	paths := synchronized
+	for _, id := range proj.buildPhaseFiles(target, "PBXSourcesBuildPhase") {
+		if path, ok := proj.resolvePath(id); ok {
+			paths = append(paths, path)
+		}
+	}
+	for _, path := range paths {
+		if !isSourceCodeFile(path) {
+			continue
+		}
+		if p.excludeRegexp != nil && p.excludeRegexp.MatchString(path) {
+			continue
+		}
+		p.sourceFilePaths = append(p.sourceFilePaths, path)
+	}
+	// Extracting nothing is most likely a project this tool does not understand.
+	if len(p.sourceFilePaths) <= 0 {
+		return errors.File(proj.filepath, fmt.Sprintf("target `%v` has no source files", stringOf(target, "name")))
+	}
+	p.findXcodeprojInterfaceBuilderFiles(proj, target, synchronized)
+
+	lprojParent, ok := proj.lprojParent(target, synchronized)
+	if !ok {
+		lprojParent = p.rootPath
+	}
+	// Regions without lproj are not localized yet.
+	for _, region := range proj.knownRegions() {
+		lproj := filepath.Join(lprojParent, region+".lproj")
+		if info, err := os.Stat(lproj); err == nil && info.IsDir() {
+			p.lprojs = append(p.lprojs, lproj)
+			if region == p.devlang {
+				p.devLproj = lproj
+			}
+		}
+	}
+	if p.devLproj == "" && len(p.stringCatalogPaths) <= 0 {
+		return errors.File(filepath.Join(lprojParent, p.devlang+".lproj"), "directory not found")
+	}
+	return nil
+}
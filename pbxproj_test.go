@@ -0,0 +1,362 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testPBXProj = `// !$*UTF8*$!
+{
+	archiveVersion = 1;
+	classes = {
+	};
+	objectVersion = 56;
+	objects = {
+
+/* Begin PBXBuildFile section */
+		B0000001 /* A.swift in Sources */ = {isa = PBXBuildFile; fileRef = F0000001 /* A.swift */; };
+		B0000002 /* Localizable.strings in Resources */ = {isa = PBXBuildFile; fileRef = V0000001 /* Localizable.strings */; };
+		B0000003 /* C.swift in Sources */ = {isa = PBXBuildFile; fileRef = F0000003 /* C.swift */; };
+		B0000004 /* Main.storyboard in Resources */ = {isa = PBXBuildFile; fileRef = V0000002 /* Main.storyboard */; };
+/* End PBXBuildFile section */
+
+/* Begin PBXFileReference section */
+		F0000001 /* A.swift */ = {isa = PBXFileReference; lastKnownFileType = sourcecode.swift; path = A.swift; sourceTree = "<group>"; };
+		F0000002 /* B.swift */ = {isa = PBXFileReference; lastKnownFileType = sourcecode.swift; path = B.swift; sourceTree = "<group>"; };
+		F0000003 /* C.swift */ = {isa = PBXFileReference; lastKnownFileType = sourcecode.swift; path = Other/C.swift; sourceTree = SOURCE_ROOT; };
+		F0000004 /* en */ = {isa = PBXFileReference; lastKnownFileType = text.plist.strings; name = en; path = en.lproj/Localizable.strings; sourceTree = "<group>"; };
+		F0000005 /* ja */ = {isa = PBXFileReference; lastKnownFileType = text.plist.strings; name = ja; path = ja.lproj/Localizable.strings; sourceTree = "<group>"; };
+		F0000006 /* Base */ = {isa = PBXFileReference; lastKnownFileType = file.storyboard; name = Base; path = Base.lproj/Main.storyboard; sourceTree = "<group>"; };
+/* End PBXFileReference section */
+
+/* Begin PBXGroup section */
+		G0000001 = {
+			isa = PBXGroup;
+			children = (
+				G0000002 /* App */,
+			);
+			sourceTree = "<group>";
+		};
+		G0000002 /* App */ = {
+			isa = PBXGroup;
+			children = (
+				F0000001 /* A.swift */,
+				F0000002 /* B.swift */,
+				V0000001 /* Localizable.strings */,
+				V0000002 /* Main.storyboard */,
+			);
+			path = App;
+			sourceTree = "<group>";
+		};
+/* End PBXGroup section */
+
+/* Begin PBXNativeTarget section */
+		T0000001 /* App */ = {
+			isa = PBXNativeTarget;
+			buildPhases = (
+				S0000001 /* Sources */,
+				R0000001 /* Resources */,
+			);
+			name = App;
+		};
+		T0000002 /* Other */ = {
+			isa = PBXNativeTarget;
+			buildPhases = (
+				S0000002 /* Sources */,
+			);
+			name = Other;
+		};
+/* End PBXNativeTarget section */
+
+/* Begin PBXProject section */
+		P0000001 /* Project object */ = {
+			isa = PBXProject;
+			developmentRegion = en;
+			knownRegions = (
+				en,
+				Base,
+				ja,
+				de,
+			);
+			mainGroup = G0000001;
+			projectDirPath = "";
+			targets = (
+				T0000001 /* App */,
+				T0000002 /* Other */,
+			);
+		};
+/* End PBXProject section */
+
+/* Begin PBXResourcesBuildPhase section */
+		R0000001 /* Resources */ = {
+			isa = PBXResourcesBuildPhase;
+			files = (
+				B0000002 /* Localizable.strings in Resources */,
+				B0000004 /* Main.storyboard in Resources */,
+			);
+		};
+/* End PBXResourcesBuildPhase section */
+
+/* Begin PBXSourcesBuildPhase section */
+		S0000001 /* Sources */ = {
+			isa = PBXSourcesBuildPhase;
+			files = (
+				B0000001 /* A.swift in Sources */,
+			);
+		};
+		S0000002 /* Sources */ = {
+			isa = PBXSourcesBuildPhase;
+			files = (
+				B0000003 /* C.swift in Sources */,
+			);
+		};
+/* End PBXSourcesBuildPhase section */
+
+/* Begin PBXVariantGroup section */
+		V0000001 /* Localizable.strings */ = {
+			isa = PBXVariantGroup;
+			children = (
+				F0000004 /* en */,
+				F0000005 /* ja */,
+			);
+			name = Localizable.strings;
+			sourceTree = "<group>";
+		};
+		V0000002 /* Main.storyboard */ = {
+			isa = PBXVariantGroup;
+			children = (
+				F0000006 /* Base */,
+			);
+			name = Main.storyboard;
+			sourceTree = "<group>";
+		};
+/* End PBXVariantGroup section */
+	};
+	rootObject = P0000001 /* Project object */;
+}
+`
+
+func TestParsePBXProj(t *testing.T) {
+	proj, err := parsePBXProj(testPBXProj, "App.xcodeproj/project.pbxproj", "root")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if proj.developmentRegion() != "en" {
+		t.Errorf("%v\n", proj.developmentRegion())
+	}
+	if regions := proj.knownRegions(); !reflect.DeepEqual(regions, []string{"en", "ja", "de"}) {
+		t.Errorf("%v\n", regions)
+	}
+
+	if _, err := proj.target(""); err == nil || err.Error() != "App.xcodeproj/project.pbxproj: specify one of the targets: App, Other" {
+		t.Errorf("%v\n", err)
+	}
+	if _, err := proj.target("Unknown"); err == nil || err.Error() != "App.xcodeproj/project.pbxproj: target `Unknown` not found" {
+		t.Errorf("%v\n", err)
+	}
+
+	cases := []struct {
+		target   string
+		expected []string
+	}{
+		{"App", []string{"root/App/A.swift"}},
+		{"Other", []string{"root/Other/C.swift"}},
+	}
+	for _, c := range cases {
+		target, err := proj.target(c.target)
+		if err != nil {
+			t.Fatalf("%v\n", err)
+		}
+		actual := []string{}
+		for _, id := range proj.buildPhaseFiles(target, "PBXSourcesBuildPhase") {
+			path, _ := proj.resolvePath(id)
+			actual = append(actual, path)
+		}
+		if !reflect.DeepEqual(actual, c.expected) {
+			t.Errorf("%v\n", actual)
+		}
+	}
+
+	target, _ := proj.target("App")
+	if dir, ok := proj.lprojParent(target, nil); !ok || dir != "root/App" {
+		t.Errorf("%v %v\n", dir, ok)
+	}
+}
+
+func TestXcodeproj(t *testing.T) {
+	root, err := ioutil.TempDir("", "gogenstrings")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	defer os.RemoveAll(root)
+
+	writeFiles(t, root, map[string]string{
+		"App.xcodeproj/project.pbxproj":    testPBXProj,
+		"App/A.swift":                      `NSLocalizedString("a", comment: "")`,
+		"App/B.swift":                      `NSLocalizedString("b", comment: "")`,
+		"App/en.lproj/Localizable.strings": "",
+		"App/ja.lproj/Localizable.strings": "",
+		"App/fr.lproj/Localizable.strings": "",
+	})
+
+	// The development language is developmentRegion.
+	ctx := newGenstringsContext(genstringsOptions{
+		rootPath:        root,
+		routineName:     "NSLocalizedString",
+		xcodeprojPath:   filepath.Join(root, "App.xcodeproj"),
+		xcodeprojTarget: "App",
+	})
+	if err := ctx.genstrings(); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if ctx.devlang != "en" {
+		t.Errorf("%v\n", ctx.devlang)
+	}
+	expected := "/* No comment provided by engineer. */\n\"a\" = \"a\";\n\n"
+	for _, name := range []string{"App/en.lproj/Localizable.strings", "App/ja.lproj/Localizable.strings"} {
+		if actual, err := readFile(filepath.Join(root, name)); err != nil || actual != expected {
+			t.Errorf("%v: %q %v\n", name, actual, err)
+		}
+	}
+	// fr is not a known region.
+	if actual, err := readFile(filepath.Join(root, "App/fr.lproj/Localizable.strings")); err != nil || actual != "" {
+		t.Errorf("%q %v\n", actual, err)
+	}
+}
+
+// testSynchronizedPBXProj is in the format of Xcode 16.
+const testSynchronizedPBXProj = `// !$*UTF8*$!
+{
+	archiveVersion = 1;
+	classes = {
+	};
+	objectVersion = 77;
+	objects = {
+
+/* Begin PBXFileSystemSynchronizedBuildFileExceptionSet section */
+		E0000001 /* Exceptions for "App" folder in "App" target */ = {
+			isa = PBXFileSystemSynchronizedBuildFileExceptionSet;
+			membershipExceptions = (
+				Excluded.swift,
+				Info.plist,
+			);
+			target = T0000001 /* App */;
+		};
+/* End PBXFileSystemSynchronizedBuildFileExceptionSet section */
+
+/* Begin PBXFileSystemSynchronizedRootGroup section */
+		S0000001 /* App */ = {
+			isa = PBXFileSystemSynchronizedRootGroup;
+			exceptions = (
+				E0000001 /* Exceptions for "App" folder in "App" target */,
+			);
+			path = App;
+			sourceTree = "<group>";
+		};
+/* End PBXFileSystemSynchronizedRootGroup section */
+
+/* Begin PBXGroup section */
+		G0000001 = {
+			isa = PBXGroup;
+			children = (
+				S0000001 /* App */,
+			);
+			sourceTree = "<group>";
+		};
+/* End PBXGroup section */
+
+/* Begin PBXNativeTarget section */
+		T0000001 /* App */ = {
+			isa = PBXNativeTarget;
+			buildPhases = (
+				P0000002 /* Sources */,
+			);
+			fileSystemSynchronizedGroups = (
+				S0000001 /* App */,
+			);
+			name = App;
+		};
+		T0000002 /* Empty */ = {
+			isa = PBXNativeTarget;
+			buildPhases = (
+			);
+			name = Empty;
+		};
+/* End PBXNativeTarget section */
+
+/* Begin PBXProject section */
+		P0000001 /* Project object */ = {
+			isa = PBXProject;
+			developmentRegion = en;
+			knownRegions = (
+				en,
+				Base,
+				ja,
+			);
+			mainGroup = G0000001;
+			projectDirPath = "";
+			targets = (
+				T0000001 /* App */,
+				T0000002 /* Empty */,
+			);
+		};
+/* End PBXProject section */
+
+/* Begin PBXSourcesBuildPhase section */
+		P0000002 /* Sources */ = {
+			isa = PBXSourcesBuildPhase;
+			files = (
+			);
+		};
+/* End PBXSourcesBuildPhase section */
+	};
+	rootObject = P0000001 /* Project object */;
+}
+`
+
+func TestXcodeprojSynchronizedGroup(t *testing.T) {
+	root, err := ioutil.TempDir("", "gogenstrings")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	defer os.RemoveAll(root)
+
+	writeFiles(t, root, map[string]string{
+		"App.xcodeproj/project.pbxproj":              testSynchronizedPBXProj,
+		"App/A.swift":                                `NSLocalizedString("a", comment: "")`,
+		"App/Excluded.swift":                         `NSLocalizedString("excluded", comment: "")`,
+		"App/Resources/en.lproj/Localizable.strings": "",
+		"App/Resources/ja.lproj/Localizable.strings": "",
+	})
+
+	ctx := newGenstringsContext(genstringsOptions{
+		rootPath:        root,
+		routineName:     "NSLocalizedString",
+		xcodeprojPath:   filepath.Join(root, "App.xcodeproj"),
+		xcodeprojTarget: "App",
+	})
+	if err := ctx.genstrings(); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	expected := "/* No comment provided by engineer. */\n\"a\" = \"a\";\n\n"
+	for _, name := range []string{"App/Resources/en.lproj/Localizable.strings", "App/Resources/ja.lproj/Localizable.strings"} {
+		if actual, err := readFile(filepath.Join(root, name)); err != nil || actual != expected {
+			t.Errorf("%v: %q %v\n", name, actual, err)
+		}
+	}
+
+	// A target without source files is an error.
+	ctx = newGenstringsContext(genstringsOptions{
+		rootPath:        root,
+		routineName:     "NSLocalizedString",
+		xcodeprojPath:   filepath.Join(root, "App.xcodeproj"),
+		xcodeprojTarget: "Empty",
+	})
+	err = ctx.genstrings()
+	if err == nil || err.Error() != filepath.Join(root, "App.xcodeproj/project.pbxproj")+": target `Empty` has no source files" {
+		t.Errorf("%v\n", err)
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// pluralCategoryOrder is the order of CLDR plural categories.
+var pluralCategoryOrder = []string{"zero", "one", "two", "few", "many", "other"}
+
+// pluralCategoriesByLanguage are the CLDR cardinal plural categories
+// of languages having more than `other`.
+var pluralCategoriesByLanguage = map[string][]string{}
+
+func init() {
+	groups := []struct {
+		categories []string
+		languages  []string
+	}{
+		{
+			[]string{"one", "other"},
+			[]string{
+				"af", "am", "as", "az", "bg", "bn", "da", "de", "el", "en",
+				"et", "eu", "fa", "fi", "fil", "gl", "gu", "hi", "hu", "hy",
+				"is", "ka", "kk", "kn", "ky", "mk", "ml", "mn", "mr", "nb",
+				"ne", "nl", "nn", "no", "or", "pa", "ps", "si", "sq", "sv",
+				"sw", "ta", "te", "tk", "tr", "ur", "uz", "zu",
+			},
+		},
+		{
+			[]string{"one", "many", "other"},
+			[]string{"ca", "es", "fr", "it", "pt"},
+		},
+		{
+			[]string{"one", "few", "other"},
+			[]string{"bs", "hr", "ro", "sr"},
+		},
+		{
+			[]string{"one", "few", "many", "other"},
+			[]string{"be", "cs", "lt", "pl", "ru", "sk", "uk"},
+		},
+		{
+			[]string{"zero", "one", "other"},
+			[]string{"lv"},
+		},
+		{
+			[]string{"one", "two", "other"},
+			[]string{"he", "iw"},
+		},
+		{
+			[]string{"one", "two", "few", "other"},
+			[]string{"gd", "sl"},
+		},
+		{
+			[]string{"one", "two", "few", "many", "other"},
+			[]string{"ga", "mt"},
+		},
+		{
+			[]string{"zero", "one", "two", "few", "many", "other"},
+			[]string{"ar", "cy"},
+		},
+	}
+	for _, group := range groups {
+		for _, language := range group.languages {
+			pluralCategoriesByLanguage[language] = group.categories
+		}
+	}
+}
+
+// pluralCategoriesOfLproj returns the plural categories
+// required by the language of lproj, e.g. "path/to/pt-BR.lproj".
+func pluralCategoriesOfLproj(lproj string) []string {
+	language := strings.TrimSuffix(filepath.Base(lproj), ".lproj")
+	if i := strings.IndexAny(language, "-_"); i >= 0 {
+		language = language[:i]
+	}
+	language = strings.ToLower(language)
+	if categories, ok := pluralCategoriesByLanguage[language]; ok {
+		return categories
+	}
+	return []string{"other"}
+}
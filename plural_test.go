@@ -0,0 +1,27 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPluralCategoriesOfLproj(t *testing.T) {
+	cases := []struct {
+		lproj    string
+		expected []string
+	}{
+		{"a/en.lproj", []string{"one", "other"}},
+		{"a/ja.lproj", []string{"other"}},
+		{"a/zh-Hans.lproj", []string{"other"}},
+		{"a/pt-BR.lproj", []string{"one", "many", "other"}},
+		{"a/ru.lproj", []string{"one", "few", "many", "other"}},
+		{"a/ar.lproj", []string{"zero", "one", "two", "few", "many", "other"}},
+		{"a/en_GB.lproj", []string{"one", "other"}},
+	}
+	for _, c := range cases {
+		actual := pluralCategoriesOfLproj(c.lproj)
+		if !reflect.DeepEqual(actual, c.expected) {
+			t.Errorf("%v: %v\n", c.lproj, actual)
+		}
+	}
+}
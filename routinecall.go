@@ -3,68 +3,137 @@ package main
 import (
 	"fmt"
 	"path"
+	"sort"
 
 	"github.com/iawaknahc/gogenstrings/errors"
 )
 
+// defaultTable is the table used when tableName is not specified.
+const defaultTable = "Localizable"
+
 type routineCall struct {
 	filepath  string
 	startLine int
 	startCol  int
+	endLine   int
+	endCol    int
 	key       string
 	comment   string
+	table     string
+	value     string
+	// withFormat is whether the call is the format of
+	// localizedStringWithFormat, i.e. the key can be plural.
+	withFormat bool
+}
+
+func (rc routineCall) tableName() string {
+	if rc.table == "" {
+		return defaultTable
+	}
+	return rc.table
 }
 
 type routineCallSlice []routineCall
 
+// tables returns the sorted table names in use.
+// The default table is always included.
+func (p routineCallSlice) tables() []string {
+	seen := map[string]bool{
+		defaultTable: true,
+	}
+	out := []string{defaultTable}
+	for _, call := range p {
+		table := call.tableName()
+		if !seen[table] {
+			seen[table] = true
+			out = append(out, table)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (p routineCallSlice) groupByTable() map[string]routineCallSlice {
+	out := map[string]routineCallSlice{}
+	for _, call := range p {
+		table := call.tableName()
+		out[table] = append(out[table], call)
+	}
+	return out
+}
+
+// toMap reports every invalid call.
 func (p routineCallSlice) toMap() (map[string]routineCall, error) {
 	out := map[string]routineCall{}
+	errs := errors.List{}
 	for _, call := range p {
 		// Validate every call has non-empty key
 		if call.key == "" {
-			return nil, errors.FileLineCol(
+			errs = append(errs, errors.FileLineCol(
 				call.filepath,
 				call.startLine,
 				call.startCol,
 				"routine call has empty key",
-			)
+			).WithEnd(call.endLine, call.endCol).WithRule(ruleEmptyKey))
+			continue
 		}
 
 		// Validate calls having the same key has the same comment
 		existingCall, ok := out[call.key]
 		if ok {
 			if call.comment != existingCall.comment {
-				return nil, errors.FileLineCol(
+				errs = append(errs, errors.FileLineCol(
 					call.filepath,
 					call.startLine,
 					call.startCol,
 					fmt.Sprintf("routine call `%v` has different comment", call.key),
-				)
+				).WithEnd(call.endLine, call.endCol).WithRule(ruleInconsistentComment))
 			}
+			if call.value != existingCall.value {
+				errs = append(errs, errors.FileLineCol(
+					call.filepath,
+					call.startLine,
+					call.startCol,
+					fmt.Sprintf("routine call `%v` has different value", call.key),
+				).WithEnd(call.endLine, call.endCol).WithRule(ruleInconsistentValue))
+			}
+			// The key is plural if any call is.
+			if call.withFormat && !existingCall.withFormat {
+				existingCall.withFormat = true
+				out[call.key] = existingCall
+			}
+			continue
 		}
 
 		out[call.key] = call
 	}
+	if len(errs) > 0 {
+		return out, errs
+	}
 	return out, nil
 }
 
-func parseRoutineCalls(src, routineName, filepath string) (routineCallSlice, error) {
-	var lexString func(stateFn) stateFn
+func parseRoutineCalls(src, routineName, filepath string, swiftUI bool) (routineCallSlice, error) {
+	var sourceSyntax syntax
+	swift := false
 	switch path.Ext(filepath) {
 	case ".swift":
-		lexString = lexStringSwift
+		sourceSyntax = swiftSyntax
+		swift = true
 	case ".m", ".h":
-		lexString = lexStringObjc
+		sourceSyntax = objcSyntax
 	default:
 		return nil, errors.File(
 			filepath,
 			"unknown file type",
 		)
 	}
-	l := newLexerWithString(src, filepath, lexString, lexRoutineCall)
+	l := newLexerWithSyntax(src, filepath, sourceSyntax, lexRoutineCall)
 	p := &routineCallParser{
 		filepath:    filepath,
 		routineName: routineName,
+		swift:       swift,
+		swiftUI:     swift && swiftUI,
 		lexer:       &l,
 	}
 	return p.parse()
@@ -73,9 +142,14 @@ func parseRoutineCalls(src, routineName, filepath string) (routineCallSlice, err
 type routineCallParser struct {
 	filepath    string
 	routineName string
+	swift       bool
+	swiftUI     bool
 	lexer       *lexer
 	peekCount   int
 	token       [1]lexItem
+	// consumed is the last non-space tokens consumed.
+	// The last one is the end of a routine call.
+	consumed []lexItem
 }
 
 func (p *routineCallParser) next() lexItem {
@@ -89,15 +163,23 @@ func (p *routineCallParser) next() lexItem {
 
 func (p *routineCallParser) backup() {
 	p.peekCount++
+	if len(p.consumed) > 0 {
+		p.consumed = p.consumed[:len(p.consumed)-1]
+	}
 }
 
 func (p *routineCallParser) nextNonSpace() (item lexItem) {
 	for {
 		item = p.next()
-		if item.Type != itemSpaces {
+		if item.Type != itemSpaces && item.Type != itemComment {
 			break
 		}
 	}
+	// Only one token can be backed up so two are enough.
+	if len(p.consumed) >= 2 {
+		p.consumed = p.consumed[1:]
+	}
+	p.consumed = append(p.consumed, item)
 	return item
 }
 
@@ -129,7 +211,11 @@ func (p *routineCallParser) unexpected(item lexItem) {
 
 func (p *routineCallParser) parse() (output routineCallSlice, outerr error) {
 	defer p.recover(&outerr)
+	withFormat := false
 	for {
+		// Only the call right after localizedStringWithFormat is the format.
+		format := withFormat
+		withFormat = false
 		token := p.nextNonSpace()
 		if token.Type == itemEOF {
 			break
@@ -137,27 +223,178 @@ func (p *routineCallParser) parse() (output routineCallSlice, outerr error) {
 		if token.Type == itemError {
 			return nil, token.Err
 		}
-		if token.Type != itemIdentifier || token.Value != p.routineName {
+		if token.Type != itemIdentifier {
 			continue
 		}
-		p.expect(itemParenLeft)
-		key := p.parseString()
-		p.expect(itemComma)
-		p.parseFuncLabel()
-		comment := p.parseString()
-		p.expect(itemParenRight)
-		rc := routineCall{
-			filepath:  p.filepath,
-			startLine: token.StartLine,
-			startCol:  token.StartCol,
-			key:       key,
-			comment:   comment,
+		var rc routineCall
+		ok := true
+		switch token.Value {
+		case p.routineName:
+			rc = p.parseRoutineCall()
+		case p.routineName + "FromTable":
+			rc = p.parseRoutineCallFromTable()
+		case p.routineName + "FromTableInBundle":
+			rc = p.parseRoutineCallFromTableInBundle()
+		case p.routineName + "WithDefaultValue":
+			rc = p.parseRoutineCallWithDefaultValue()
+		case "String":
+			if p.swift {
+				rc, ok = p.parseStringLocalized()
+			} else {
+				ok = false
+			}
+		case "LocalizedStringResource":
+			if p.swift {
+				rc, ok = p.parseLocalizedStringResource()
+			} else {
+				ok = false
+			}
+		case "localizedStringWithFormat":
+			// String.localizedStringWithFormat(format, ...) and
+			// [NSString localizedStringWithFormat:format, ...]
+			next := p.nextNonSpace()
+			withFormat = next.Type == itemParenLeft || next.Type == itemColon
+			if !withFormat {
+				p.backup()
+			}
+			ok = false
+		default:
+			if p.swiftUI && isSwiftUILocalizable(token.Value) {
+				rc, ok = p.parseSwiftUI()
+			} else {
+				ok = false
+			}
+		}
+		if !ok {
+			continue
 		}
+		rc.filepath = p.filepath
+		rc.withFormat = format
+		rc.startLine = token.StartLine
+		rc.startCol = token.StartCol
+		// The end is exclusive. It is after the last character
+		// because the last character can end a line.
+		last := p.consumed[len(p.consumed)-1]
+		rc.endLine, rc.endCol = p.lexer.lineCol(last.End - 1)
+		rc.endCol++
 		output = append(output, rc)
 	}
 	return output, nil
 }
 
+// parseRoutineCall parses
+// NSLocalizedString(key, comment) and
+// NSLocalizedString(key, tableName: table, bundle: bundle, value: value, comment: comment)
+func (p *routineCallParser) parseRoutineCall() (rc routineCall) {
+	p.expect(itemParenLeft)
+	rc.key = p.parseString()
+Loop:
+	for {
+		p.expect(itemComma)
+		label, ok := p.parseFuncLabel()
+		if !ok {
+			// The comment is the last argument.
+			rc.comment = p.parseString()
+			break
+		}
+		switch label.Value {
+		case "tableName":
+			rc.table = p.parseString()
+		case "bundle":
+			p.skipExpression()
+		case "value":
+			rc.value = p.parseString()
+		case "comment":
+			rc.comment = p.parseString()
+			break Loop
+		default:
+			p.unexpected(label)
+		}
+	}
+	p.expect(itemParenRight)
+	return
+}
+
+// parseRoutineCallFromTable parses
+// NSLocalizedStringFromTable(key, table, comment)
+func (p *routineCallParser) parseRoutineCallFromTable() (rc routineCall) {
+	p.expect(itemParenLeft)
+	rc.key = p.parseString()
+	p.expect(itemComma)
+	rc.table = p.parseString()
+	p.expect(itemComma)
+	rc.comment = p.parseString()
+	p.expect(itemParenRight)
+	return
+}
+
+// parseRoutineCallFromTableInBundle parses
+// NSLocalizedStringFromTableInBundle(key, table, bundle, comment)
+func (p *routineCallParser) parseRoutineCallFromTableInBundle() (rc routineCall) {
+	p.expect(itemParenLeft)
+	rc.key = p.parseString()
+	p.expect(itemComma)
+	rc.table = p.parseString()
+	p.expect(itemComma)
+	p.skipExpression()
+	p.expect(itemComma)
+	rc.comment = p.parseString()
+	p.expect(itemParenRight)
+	return
+}
+
+// parseRoutineCallWithDefaultValue parses
+// NSLocalizedStringWithDefaultValue(key, table, bundle, value, comment)
+func (p *routineCallParser) parseRoutineCallWithDefaultValue() (rc routineCall) {
+	p.expect(itemParenLeft)
+	rc.key = p.parseString()
+	p.expect(itemComma)
+	rc.table = p.parseString()
+	p.expect(itemComma)
+	p.skipExpression()
+	p.expect(itemComma)
+	rc.value = p.parseString()
+	p.expect(itemComma)
+	rc.comment = p.parseString()
+	p.expect(itemParenRight)
+	return
+}
+
+// skipExpression skips an arbitrary argument expression
+// such as Bundle.main or [NSBundle bundleForClass:[self class]].
+// It stops before the comma or right paren ending the argument.
+func (p *routineCallParser) skipExpression() {
+	depth := 0
+	first := true
+	for {
+		token := p.nextNonSpace()
+		switch token.Type {
+		case itemEOF, itemError:
+			p.unexpected(token)
+		case itemParenLeft:
+			depth++
+		case itemParenRight:
+			if depth <= 0 {
+				if first {
+					p.unexpected(token)
+				}
+				p.backup()
+				return
+			}
+			depth--
+		case itemComma:
+			if depth <= 0 {
+				if first {
+					p.unexpected(token)
+				}
+				p.backup()
+				return
+			}
+		}
+		first = false
+	}
+}
+
 func (p *routineCallParser) parseString() (output string) {
 	atSign := false
 	token := p.nextNonSpace()
@@ -195,11 +432,12 @@ func (p *routineCallParser) parseString() (output string) {
 	return output
 }
 
-func (p *routineCallParser) parseFuncLabel() {
+func (p *routineCallParser) parseFuncLabel() (lexItem, bool) {
 	token := p.nextNonSpace()
 	if token.Type != itemIdentifier {
 		p.backup()
-		return
+		return token, false
 	}
 	p.expect(itemColon)
+	return token, true
 }
@@ -3,6 +3,8 @@ package main
 import (
 	"reflect"
 	"testing"
+
+	"github.com/iawaknahc/gogenstrings/errors"
 )
 
 func TestParseRoutineCalls(t *testing.T) {
@@ -27,6 +29,8 @@ class MyView: UILabel {
 			filepath:  ".swift",
 			startLine: 7,
 			startCol:  15,
+			endLine:   7,
+			endCol:    60,
 			key:       "key1",
 			comment:   "comment",
 		},
@@ -34,6 +38,8 @@ class MyView: UILabel {
 			filepath:  ".swift",
 			startLine: 8,
 			startCol:  15,
+			endLine:   8,
+			endCol:    60,
 			key:       "key2",
 			comment:   "comment",
 		},
@@ -41,6 +47,8 @@ class MyView: UILabel {
 			filepath:  ".swift",
 			startLine: 9,
 			startCol:  15,
+			endLine:   9,
+			endCol:    53,
 			key:       "key1",
 			comment:   "comment",
 		},
@@ -48,6 +56,8 @@ class MyView: UILabel {
 			filepath:  ".swift",
 			startLine: 10,
 			startCol:  15,
+			endLine:   10,
+			endCol:    52,
 			key:       "key1",
 			comment:   "comment",
 		},
@@ -55,6 +65,8 @@ class MyView: UILabel {
 			filepath:  ".swift",
 			startLine: 11,
 			startCol:  15,
+			endLine:   11,
+			endCol:    52,
 			key:       "key1",
 			comment:   "comment",
 		},
@@ -62,15 +74,17 @@ class MyView: UILabel {
 			filepath:  ".swift",
 			startLine: 12,
 			startCol:  15,
+			endLine:   12,
+			endCol:    51,
 			key:       "key1",
 			comment:   "comment",
 		},
 	}
-	actual, err := parseRoutineCalls(input, routineName, ".swift")
+	actual, err := parseRoutineCalls(input, routineName, ".swift", false)
 	if err != nil {
 		t.Fail()
 	} else if !reflect.DeepEqual(actual, expected) {
-		t.Fail()
+		t.Errorf("%v\n", actual)
 	}
 }
 
@@ -98,6 +112,21 @@ func TestRoutineCallSliceToMap(t *testing.T) {
 		t.Fail()
 	}
 
+	input = routineCallSlice{
+		routineCall{
+			key:   "a",
+			value: "1",
+		},
+		routineCall{
+			key:   "a",
+			value: "2",
+		},
+	}
+	actual, err = input.toMap()
+	if err == nil {
+		t.Fail()
+	}
+
 	input = routineCallSlice{
 		routineCall{
 			key:     "a",
@@ -115,3 +144,271 @@ func TestRoutineCallSliceToMap(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestRoutineCallSliceToMapEnd(t *testing.T) {
+	input, err := parseRoutineCalls("NSLocalizedString(\"a\", comment: \"1\")\nNSLocalizedString(\"a\",\n  comment: \"2\")\n", "NSLocalizedString", ".swift", false)
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	_, err = input.toMap()
+	expected := []errors.Diagnostic{
+		{RuleID: "inconsistent-comment", Severity: "error", Filepath: ".swift", Line: 2, Col: 1, EndLine: 3, EndCol: 16, Message: "routine call `a` has different comment"},
+	}
+	if actual := errors.ToDiagnostics(err); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("%v\n", actual)
+	}
+}
+
+func TestParseRoutineCallsTableName(t *testing.T) {
+	routineName := "NSLocalizedString"
+	input := `
+NSLocalizedString("key1", tableName: "Settings", comment: "comment")
+NSLocalizedStringFromTable(@"key2", @"Settings", @"comment")
+NSLocalizedString("key3", comment: "comment")
+`
+	expected := routineCallSlice{
+		routineCall{
+			filepath:  ".swift",
+			startLine: 2,
+			startCol:  1,
+			endLine:   2,
+			endCol:    69,
+			key:       "key1",
+			comment:   "comment",
+			table:     "Settings",
+		},
+		routineCall{
+			filepath:  ".swift",
+			startLine: 3,
+			startCol:  1,
+			endLine:   3,
+			endCol:    61,
+			key:       "key2",
+			comment:   "comment",
+			table:     "Settings",
+		},
+		routineCall{
+			filepath:  ".swift",
+			startLine: 4,
+			startCol:  1,
+			endLine:   4,
+			endCol:    46,
+			key:       "key3",
+			comment:   "comment",
+		},
+	}
+	actual, err := parseRoutineCalls(input, routineName, ".swift", false)
+	if err != nil {
+		t.Errorf("%v\n", err)
+	} else if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("%v\n", actual)
+	}
+
+	_, err = parseRoutineCalls(`NSLocalizedString("key", foo: "", comment: "")`, routineName, ".swift", false)
+	if err == nil || err.Error() != ".swift:1:26: unexpected token `<ident>`" {
+		t.Errorf("%v\n", err)
+	}
+}
+
+func TestRoutineCallSliceTables(t *testing.T) {
+	input := routineCallSlice{
+		routineCall{
+			key:   "a",
+			table: "Settings",
+		},
+		routineCall{
+			key:   "a",
+			table: "Localizable",
+		},
+		routineCall{
+			key: "b",
+		},
+	}
+	expected := []string{"Localizable", "Settings"}
+	if actual := input.tables(); !reflect.DeepEqual(actual, expected) {
+		t.Fail()
+	}
+
+	groups := input.groupByTable()
+	if len(groups["Localizable"]) != 2 || len(groups["Settings"]) != 1 {
+		t.Fail()
+	}
+}
+
+func TestParseRoutineCallsBundleValue(t *testing.T) {
+	routineName := "NSLocalizedString"
+	input := `
+NSLocalizedString("key1", tableName: "Settings", bundle: Bundle(for: type(of: self)), value: "value", comment: "comment")
+NSLocalizedString("key2", bundle: .main, comment: "comment")
+NSLocalizedStringFromTableInBundle(@"key3", @"Settings", [NSBundle bundleForClass:[self class]], @"comment")
+NSLocalizedStringWithDefaultValue(@"key4", @"Settings", [NSBundle mainBundle], @"value", @"comment")
+`
+	expected := routineCallSlice{
+		routineCall{
+			filepath:  ".swift",
+			startLine: 2,
+			startCol:  1,
+			endLine:   2,
+			endCol:    122,
+			key:       "key1",
+			comment:   "comment",
+			table:     "Settings",
+			value:     "value",
+		},
+		routineCall{
+			filepath:  ".swift",
+			startLine: 3,
+			startCol:  1,
+			endLine:   3,
+			endCol:    61,
+			key:       "key2",
+			comment:   "comment",
+		},
+		routineCall{
+			filepath:  ".swift",
+			startLine: 4,
+			startCol:  1,
+			endLine:   4,
+			endCol:    109,
+			key:       "key3",
+			comment:   "comment",
+			table:     "Settings",
+		},
+		routineCall{
+			filepath:  ".swift",
+			startLine: 5,
+			startCol:  1,
+			endLine:   5,
+			endCol:    101,
+			key:       "key4",
+			comment:   "comment",
+			table:     "Settings",
+			value:     "value",
+		},
+	}
+	actual, err := parseRoutineCalls(input, routineName, ".swift", false)
+	if err != nil {
+		t.Errorf("%v\n", err)
+	} else if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("%v\n", actual)
+	}
+
+	_, err = parseRoutineCalls(`NSLocalizedString("key", bundle: , comment: "")`, routineName, ".swift", false)
+	if err == nil || err.Error() != ".swift:1:34: unexpected token `,`" {
+		t.Errorf("%v\n", err)
+	}
+}
+
+func TestParseRoutineCallsComment(t *testing.T) {
+	routineName := "NSLocalizedString"
+	input := `
+// NSLocalizedString("old.key1", comment: "")
+/// Use NSLocalizedString("old.key2", comment: "") to localize.
+/*
+ /* NSLocalizedString("old.key3", comment: "") */
+ NSLocalizedString("old.key4", comment: "")
+*/
+NSLocalizedString("key", /* the key */ comment: "comment") // trailing
+`
+	expected := routineCallSlice{
+		routineCall{
+			filepath:  ".swift",
+			startLine: 8,
+			startCol:  1,
+			endLine:   8,
+			endCol:    59,
+			key:       "key",
+			comment:   "comment",
+		},
+	}
+	actual, err := parseRoutineCalls(input, routineName, ".swift", false)
+	if err != nil {
+		t.Errorf("%v\n", err)
+	} else if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("%v\n", actual)
+	}
+
+	input = `
+// NSLocalizedString(@"old.key1", @"")
+/* NSLocalizedString(@"old.key2", @"") */
+NSLocalizedString(@"key", @"comment");
+`
+	expected = routineCallSlice{
+		routineCall{
+			filepath:  ".m",
+			startLine: 4,
+			startCol:  1,
+			endLine:   4,
+			endCol:    38,
+			key:       "key",
+			comment:   "comment",
+		},
+	}
+	actual, err = parseRoutineCalls(input, routineName, ".m", false)
+	if err != nil {
+		t.Errorf("%v\n", err)
+	} else if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("%v\n", actual)
+	}
+}
+
+func TestParseRoutineCallsWithFormat(t *testing.T) {
+	routineName := "NSLocalizedString"
+	cases := []struct {
+		input    string
+		filepath string
+	}{
+		{`String.localizedStringWithFormat(NSLocalizedString("a", comment: ""), n)
+NSLocalizedString("b", comment: "")`, ".swift"},
+		{`[NSString localizedStringWithFormat:NSLocalizedString(@"a", @""), n];
+NSLocalizedString(@"b", @"");`, ".m"},
+	}
+	for _, c := range cases {
+		actual, err := parseRoutineCalls(c.input, routineName, c.filepath, false)
+		if err != nil {
+			t.Errorf("%v\n", err)
+		} else if len(actual) != 2 || !actual[0].withFormat || actual[1].withFormat {
+			t.Errorf("%+v\n", actual)
+		}
+	}
+
+	// The key is plural if any call is.
+	input, err := parseRoutineCalls(`NSLocalizedString("a", comment: "")
+String.localizedStringWithFormat(NSLocalizedString("a", comment: ""), n)`, routineName, ".swift", false)
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if actual, err := input.toMap(); err != nil || !actual["a"].withFormat {
+		t.Errorf("%+v %v\n", actual, err)
+	}
+}
+
+func TestParseRoutineCallsSwiftStringLiteral(t *testing.T) {
+	routineName := "NSLocalizedString"
+	input := `
+#if DEBUG
+NSLocalizedString(#"key "1""#, comment: """
+    A long comment
+      spanning \
+    lines
+    """)
+#endif
+`
+	expected := routineCallSlice{
+		routineCall{
+			filepath:  ".swift",
+			startLine: 3,
+			startCol:  1,
+			endLine:   7,
+			endCol:    9,
+			key:       `key "1"`,
+			comment:   "A long comment\n  spanning lines",
+		},
+	}
+	actual, err := parseRoutineCalls(input, routineName, ".swift", false)
+	if err != nil {
+		t.Errorf("%v\n", err)
+	} else if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("%v\n", actual)
+	}
+}
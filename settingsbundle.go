@@ -0,0 +1,182 @@
+package main
+
+import (
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/iawaknahc/gogenstrings/errors"
+	"github.com/iawaknahc/gogenstrings/xmlplist"
+)
+
+const dotPlistExt = ".plist"
+
+// settingsBundleLocalizableKeys are the keys of
+// PreferenceSpecifiers which are localized.
+var settingsBundleLocalizableKeys = []string{"Title", "Titles", "FooterText"}
+
+func expectSettingsString(filepath string, value xmlplist.Value) string {
+	s, ok := value.Value.(string)
+	if !ok {
+		panic(errors.FileLineCol(filepath, value.Line, value.Col, "expected <string>"))
+	}
+	return s
+}
+
+// parseSettingsPlist returns the table and the localizable strings
+// of a plist in Settings.bundle, e.g. Root.plist.
+// The key of a string is the string itself.
+func parseSettingsPlist(src, filepath string) (table string, out entries, err error) {
+	value, err := xmlplist.ParseXMLPlist(src, filepath)
+	if err != nil {
+		return "", nil, err
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			e, ok := r.(error)
+			if !ok {
+				panic("panicked without error")
+			}
+			err = e
+		}
+	}()
+
+	dict, ok := value.Value.(xmlplist.Dict)
+	if !ok {
+		return "", nil, errors.FileLineCol(filepath, value.Line, value.Col, "expected <dict>")
+	}
+
+	table = strings.TrimSuffix(path.Base(filepath), dotPlistExt)
+	if stringsTable, ok := dict.Get("StringsTable"); ok {
+		table = expectSettingsString(filepath, stringsTable)
+	}
+
+	out = entries{}
+	add := func(value xmlplist.Value) {
+		s := expectSettingsString(filepath, value)
+		if s == "" {
+			return
+		}
+		out = append(out, entry{
+			filepath:  filepath,
+			startLine: value.Line,
+			startCol:  value.Col,
+			key:       s,
+			value:     s,
+		})
+	}
+
+	specifiers, ok := dict.Get("PreferenceSpecifiers")
+	if !ok {
+		return table, out, nil
+	}
+	array, ok := specifiers.Value.([]interface{})
+	if !ok {
+		return "", nil, errors.FileLineCol(filepath, specifiers.Line, specifiers.Col, "expected <array>")
+	}
+	for _, item := range array {
+		specifier := item.(xmlplist.Value)
+		specifierDict, ok := specifier.Value.(xmlplist.Dict)
+		if !ok {
+			return "", nil, errors.FileLineCol(filepath, specifier.Line, specifier.Col, "expected <dict>")
+		}
+		for _, key := range settingsBundleLocalizableKeys {
+			v, ok := specifierDict.Get(key)
+			if !ok {
+				continue
+			}
+			// Titles of multi value specifier
+			if titles, ok := v.Value.([]interface{}); ok {
+				for _, title := range titles {
+					add(title.(xmlplist.Value))
+				}
+				continue
+			}
+			add(v)
+		}
+	}
+	return table, out, nil
+}
+
+// findSettingsBundle finds the plists and the lprojs of Settings.bundle.
+func (p *genstringsContext) findSettingsBundle() error {
+	if p.settingsBundlePath == "" {
+		return nil
+	}
+	plistPaths, err := filepath.Glob(filepath.Join(p.settingsBundlePath, "*"+dotPlistExt))
+	if err != nil {
+		return err
+	}
+	sort.Strings(plistPaths)
+	p.settingsPlistPaths = plistPaths
+
+	lprojs, err := findLprojs(p.settingsBundlePath)
+	if err != nil {
+		return err
+	}
+	p.settingsLprojs = lprojs
+	for _, lproj := range lprojs {
+		if filepath.Base(lproj) == p.devlang+".lproj" {
+			p.settingsDevLproj = lproj
+			return nil
+		}
+	}
+	return errors.File(filepath.Join(p.settingsBundlePath, p.devlang+".lproj"), "directory not found")
+}
+
+func (p *genstringsContext) readSettingsBundle() {
+	for _, plistPath := range p.settingsPlistPaths {
+		content, err := readFile(plistPath)
+		if err != nil {
+			p.diagnostics.Add(err)
+			continue
+		}
+		table, es, err := parseSettingsPlist(content, plistPath)
+		if err != nil {
+			p.diagnostics.Add(err)
+			continue
+		}
+		// Plists can share a table.
+		p.settingsEntries[table] = append(p.settingsEntries[table], es...)
+	}
+	for table := range p.settingsEntries {
+		in := make(map[string]entries)
+		p.readDotStringsIn(p.settingsLprojs, table+dotStringsExt, in)
+		p.inSettingsEntries[table] = in
+	}
+}
+
+func (p *genstringsContext) validateSettingsBundle() {
+	for table, es := range p.settingsEntries {
+		// The same title can appear many times.
+		em := entryMap{}
+		for _, e := range es {
+			if _, ok := em[e.key]; !ok {
+				em[e.key] = e
+			}
+		}
+		p.settingsEntryMap[table] = em
+
+		out := make(map[string]entryMap)
+		p.validateDotStrings(p.inSettingsEntries[table], out)
+		p.inSettingsEntryMap[table] = out
+	}
+}
+
+func (p *genstringsContext) processSettingsBundle() {
+	devLproj := p.settingsDevLproj
+	for table, inEntryMap := range p.inSettingsEntryMap {
+		out := make(map[string]entryMap)
+		// Like Info.plist, the plist is the source of keys.
+		out[devLproj] = inEntryMap[devLproj].mergeInfoPlist(p.settingsEntryMap[table])
+		for lproj, em := range inEntryMap {
+			if lproj == devLproj {
+				continue
+			}
+			out[lproj] = em.mergeDev(out[devLproj])
+		}
+		p.outSettingsEntryMap[table] = out
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testSettingsRootPlist = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>StringsTable</key>
+	<string>Root</string>
+	<key>PreferenceSpecifiers</key>
+	<array>
+		<dict>
+			<key>Type</key>
+			<string>PSGroupSpecifier</string>
+			<key>Title</key>
+			<string>Group</string>
+			<key>FooterText</key>
+			<string>Footer</string>
+		</dict>
+		<dict>
+			<key>Type</key>
+			<string>PSMultiValueSpecifier</string>
+			<key>Title</key>
+			<string>Theme</string>
+			<key>Titles</key>
+			<array>
+				<string>Light</string>
+				<string>Dark</string>
+			</array>
+			<key>Values</key>
+			<array>
+				<string>light</string>
+				<string>dark</string>
+			</array>
+		</dict>
+		<dict>
+			<key>Type</key>
+			<string>PSToggleSwitchSpecifier</string>
+			<key>Title</key>
+			<string>Group</string>
+			<key>Key</key>
+			<string>group_enabled</string>
+		</dict>
+	</array>
+</dict>
+</plist>
+`
+
+func TestParseSettingsPlist(t *testing.T) {
+	table, es, err := parseSettingsPlist(testSettingsRootPlist, "Settings.bundle/Root.plist")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if table != "Root" {
+		t.Errorf("%v\n", table)
+	}
+	expected := []string{"Group", "Footer", "Theme", "Light", "Dark", "Group"}
+	if len(es) != len(expected) {
+		t.Fatalf("%+v\n", es)
+	}
+	for i, e := range es {
+		if e.key != expected[i] || e.value != expected[i] {
+			t.Errorf("%+v\n", e)
+		}
+	}
+	if es[0].startLine != 13 || es[0].startCol != 4 {
+		t.Errorf("%+v\n", es[0])
+	}
+
+	_, _, err = parseSettingsPlist(stringsdictHeader+"<dict>\n<key>PreferenceSpecifiers</key>\n<array><dict><key>Title</key><integer>1</integer></dict></array>\n</dict>\n</plist>\n", "Root.plist")
+	if err == nil || err.Error() != "Root.plist:6:30: expected <string>" {
+		t.Errorf("%v\n", err)
+	}
+}
+
+func TestSettingsBundle(t *testing.T) {
+	root, err := ioutil.TempDir("", "gogenstrings")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	defer os.RemoveAll(root)
+
+	writeFiles(t, root, map[string]string{
+		"en.lproj/Localizable.strings":          "",
+		"fr.lproj/Localizable.strings":          "",
+		"A.swift":                               `NSLocalizedString("a", comment: "")`,
+		"Settings.bundle/Root.plist":            testSettingsRootPlist,
+		"Settings.bundle/en.lproj/Root.strings": "\"Group\" = \"General\";\n\"Removed\" = \"Removed\";\n",
+		"Settings.bundle/ja.lproj/Root.strings": "\"Theme\" = \"テーマ\";\n",
+	})
+
+	ctx := newGenstringsContext(genstringsOptions{
+		rootPath:           root,
+		devlang:            "en",
+		routineName:        "NSLocalizedString",
+		settingsBundlePath: filepath.Join(root, "Settings.bundle"),
+	})
+	if err := ctx.genstrings(); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	expected := map[string]string{
+		"Settings.bundle/en.lproj/Root.strings": `"Dark" = "Dark";
+
+"Footer" = "Footer";
+
+"Group" = "General";
+
+"Light" = "Light";
+
+"Theme" = "Theme";
+
+`,
+		"Settings.bundle/ja.lproj/Root.strings": `"Dark" = "Dark";
+
+"Footer" = "Footer";
+
+"Group" = "General";
+
+"Light" = "Light";
+
+"Theme" = "テーマ";
+
+`,
+	}
+	for name, content := range expected {
+		if actual, err := readFile(filepath.Join(root, name)); err != nil || actual != content {
+			t.Errorf("%v: %q %v\n", name, actual, err)
+		}
+	}
+	// Settings.bundle has no Localizable.strings.
+	if _, err := os.Stat(filepath.Join(root, "Settings.bundle/en.lproj/Localizable.strings")); !os.IsNotExist(err) {
+		t.Errorf("%v\n", err)
+	}
+
+	ctx = newGenstringsContext(genstringsOptions{
+		rootPath:           root,
+		devlang:            "fr",
+		routineName:        "NSLocalizedString",
+		settingsBundlePath: filepath.Join(root, "Settings.bundle"),
+	})
+	// Settings.bundle has no fr.lproj.
+	err = ctx.find()
+	if err == nil || err.Error() != filepath.Join(root, "Settings.bundle/fr.lproj")+": directory not found" {
+		t.Errorf("%v\n", err)
+	}
+}
+
+func TestSettingsBundleRelativePath(t *testing.T) {
+	root, err := ioutil.TempDir("", "gogenstrings")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	defer os.RemoveAll(root)
+
+	writeFiles(t, root, map[string]string{
+		"en.lproj/Localizable.strings":          "",
+		"A.swift":                               `NSLocalizedString("a", comment: "")`,
+		"Settings.bundle/Root.plist":            testSettingsRootPlist,
+		"Settings.bundle/en.lproj/Root.strings": "",
+	})
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	relRoot, err := filepath.Rel(wd, root)
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	cases := []struct {
+		root     string
+		settings string
+	}{
+		{relRoot, filepath.Join(root, "Settings.bundle")},
+		{root, filepath.Join(relRoot, "Settings.bundle")},
+	}
+	for _, c := range cases {
+		ctx := newGenstringsContext(genstringsOptions{
+			rootPath:           c.root,
+			devlang:            "en",
+			routineName:        "NSLocalizedString",
+			settingsBundlePath: c.settings,
+		})
+		if err := ctx.find(); err != nil {
+			t.Fatalf("%v\n", err)
+		}
+		if len(ctx.lprojs) != 1 || ctx.lprojs[0] != filepath.Join(c.root, "en.lproj") {
+			t.Errorf("%v\n", ctx.lprojs)
+		}
+	}
+}
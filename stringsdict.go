@@ -0,0 +1,269 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/iawaknahc/gogenstrings/errors"
+	"github.com/iawaknahc/gogenstrings/xmlplist"
+)
+
+const (
+	dotStringsdictExt = ".stringsdict"
+
+	stringsdictFormatKey      = "NSStringLocalizedFormatKey"
+	stringsdictSpecTypeKey    = "NSStringFormatSpecTypeKey"
+	stringsdictValueTypeKey   = "NSStringFormatValueTypeKey"
+	stringsdictPluralRuleType = "NSStringPluralRuleType"
+)
+
+// stringsdictEntry is a key in .stringsdict.
+type stringsdictEntry struct {
+	filepath  string
+	startLine int
+	startCol  int
+	key       string
+	// value is the <dict> of the key.
+	// It contains only <string> and <dict>.
+	value xmlplist.Dict
+}
+
+type stringsdictEntryMap map[string]stringsdictEntry
+
+func parseStringsdict(src, filepath string) (stringsdictEntryMap, error) {
+	value, err := xmlplist.ParseXMLPlist(src, filepath)
+	if err != nil {
+		return nil, err
+	}
+	return stringsdictFromValue(value, filepath)
+}
+
+// stringsdictFromValue is like parseStringsdict
+// but the plist is already parsed.
+func stringsdictFromValue(value xmlplist.Value, filepath string) (stringsdictEntryMap, error) {
+	dict, ok := value.Value.(xmlplist.Dict)
+	if !ok {
+		return nil, fileLineColErr(
+			filepath,
+			value.Line,
+			value.Col,
+			"stringsdict is not a dict",
+		)
+	}
+
+	out := stringsdictEntryMap{}
+	for _, keyValue := range dict.Keys() {
+		key := keyValue.Value.(string)
+		valueValue, _ := dict.Get(key)
+		entryDict, ok := valueValue.Value.(xmlplist.Dict)
+		if !ok {
+			return nil, fileLineColErr(
+				filepath,
+				valueValue.Line,
+				valueValue.Col,
+				"expected <dict>",
+			)
+		}
+		if err := validateStringsdictDict(filepath, entryDict); err != nil {
+			return nil, err
+		}
+		out[key] = stringsdictEntry{
+			filepath:  filepath,
+			startLine: keyValue.Line,
+			startCol:  keyValue.Col,
+			key:       key,
+			value:     entryDict,
+		}
+	}
+	return out, nil
+}
+
+func validateStringsdictDict(filepath string, dict xmlplist.Dict) error {
+	for _, keyValue := range dict.Keys() {
+		value, _ := dict.Get(keyValue.Value.(string))
+		switch x := value.Value.(type) {
+		case string:
+		case xmlplist.Dict:
+			if err := validateStringsdictDict(filepath, x); err != nil {
+				return err
+			}
+		default:
+			return fileLineColErr(
+				filepath,
+				value.Line,
+				value.Col,
+				"expected <string> or <dict>",
+			)
+		}
+	}
+	return nil
+}
+
+// pluralRules returns the keys of the variables
+// which are plural rules.
+func (e stringsdictEntry) pluralRules() []xmlplist.Value {
+	out := []xmlplist.Value{}
+	for _, keyValue := range e.value.Keys() {
+		v, _ := e.value.Get(keyValue.Value.(string))
+		dict, ok := v.Value.(xmlplist.Dict)
+		if !ok {
+			continue
+		}
+		specType, ok := dict.Get(stringsdictSpecTypeKey)
+		if ok && specType.Value == stringsdictPluralRuleType {
+			out = append(out, keyValue)
+		}
+	}
+	return out
+}
+
+// validatePluralCategories reports every missing category.
+// A skeleton is not read from a file so targetPath is reported.
+func (e stringsdictEntry) validatePluralCategories(categories []string, language, targetPath string) error {
+	path := e.filepath
+	if path == "" {
+		path = targetPath
+	}
+	errs := errors.List{}
+	for _, keyValue := range e.pluralRules() {
+		name := keyValue.Value.(string)
+		rule, _ := e.value.Get(name)
+		dict := rule.Value.(xmlplist.Dict)
+		for _, category := range categories {
+			if _, ok := dict.Get(category); ok {
+				continue
+			}
+			errs = append(errs, positionErr(
+				rulePluralCategory,
+				path,
+				keyValue.Line,
+				keyValue.Col,
+				fmt.Sprintf(
+					"`%v` is missing plural category `%v` of `%v` for %v",
+					e.key,
+					category,
+					name,
+					language,
+				),
+			))
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func isPluralCategory(key string) bool {
+	for _, category := range pluralCategoryOrder {
+		if key == category {
+			return true
+		}
+	}
+	return false
+}
+
+// skeleton returns a copy of the receiver with
+// plural rules having exactly the given categories.
+// A category not in the receiver takes the value of `other`.
+func (e stringsdictEntry) skeleton(categories []string) stringsdictEntry {
+	value := xmlplist.NewDict()
+	for _, keyValue := range e.value.Keys() {
+		name := keyValue.Value.(string)
+		variable, _ := e.value.Get(name)
+		value.Set(name, 0, 0, xmlplist.Value{Value: variable.Value})
+	}
+	for _, keyValue := range e.pluralRules() {
+		name := keyValue.Value.(string)
+		rule, _ := e.value.Get(name)
+		ruleDict := rule.Value.(xmlplist.Dict)
+		dict := xmlplist.NewDict()
+		// Keep other keys such as NSStringFormatValueTypeKey
+		for _, k := range ruleDict.Keys() {
+			key := k.Value.(string)
+			if !isPluralCategory(key) {
+				v, _ := ruleDict.Get(key)
+				dict.Set(key, 0, 0, xmlplist.Value{Value: v.Value})
+			}
+		}
+		for _, category := range categories {
+			v, ok := ruleDict.Get(category)
+			if !ok {
+				v, ok = ruleDict.Get("other")
+			}
+			if ok {
+				dict.Set(category, 0, 0, xmlplist.Value{Value: v.Value})
+			}
+		}
+		value.Set(name, 0, 0, xmlplist.Value{Value: dict})
+	}
+	return stringsdictEntry{
+		key:   e.key,
+		value: value,
+	}
+}
+
+func (p stringsdictEntryMap) mergeCalls(calls map[string]routineCall) stringsdictEntryMap {
+	output := stringsdictEntryMap{}
+	// Copy existing entry if they are still in use.
+	// The call can be anywhere, e.g. a format stored in a variable.
+	for key, entry := range p {
+		if _, ok := calls[key]; ok {
+			output[key] = entry
+		}
+	}
+	return output
+}
+
+func (p stringsdictEntryMap) mergeDev(dev stringsdictEntryMap, categories []string) stringsdictEntryMap {
+	output := stringsdictEntryMap{}
+	for key, entry := range p {
+		if _, ok := dev[key]; ok {
+			output[key] = entry
+		}
+	}
+	// Missing entry gets a skeleton with the categories of the language
+	for key, devEntry := range dev {
+		if _, ok := output[key]; !ok {
+			output[key] = devEntry.skeleton(categories)
+		}
+	}
+	return output
+}
+
+// sortedEntries returns existing entries in the order of the file
+// followed by new entries sorted by key.
+func (p stringsdictEntryMap) sortedEntries() []stringsdictEntry {
+	out := []stringsdictEntry{}
+	for _, entry := range p {
+		out = append(out, entry)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a := out[i]
+		b := out[j]
+		if (a.filepath == "") != (b.filepath == "") {
+			return a.filepath != ""
+		}
+		if a.startLine != b.startLine {
+			return a.startLine < b.startLine
+		}
+		if a.startCol != b.startCol {
+			return a.startCol < b.startCol
+		}
+		return a.key < b.key
+	})
+	return out
+}
+
+func (p stringsdictEntryMap) print() string {
+	dict := xmlplist.NewDict()
+	for _, entry := range p.sortedEntries() {
+		dict.Set(entry.key, 0, 0, xmlplist.Value{Value: entry.value})
+	}
+	out, err := xmlplist.PrintXMLPlist(xmlplist.Value{Value: dict})
+	if err != nil {
+		// stringsdict contains only <string> and <dict>.
+		panic(fmt.Errorf("unreachable"))
+	}
+	return out
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const stringsdictHeader = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+`
+
+const devStringsdict = stringsdictHeader + `<dict>
+	<key>n_items</key>
+	<dict>
+		<key>NSStringLocalizedFormatKey</key>
+		<string>%#@items@</string>
+		<key>items</key>
+		<dict>
+			<key>NSStringFormatSpecTypeKey</key>
+			<string>NSStringPluralRuleType</string>
+			<key>NSStringFormatValueTypeKey</key>
+			<string>d</string>
+			<key>one</key>
+			<string>%d item</string>
+			<key>other</key>
+			<string>%d items</string>
+		</dict>
+	</dict>
+</dict>
+</plist>
+`
+
+func TestParseStringsdict(t *testing.T) {
+	em, err := parseStringsdict(devStringsdict, "en.lproj/Localizable.stringsdict")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	e := em["n_items"]
+	if e.startLine != 5 || e.startCol != 2 {
+		t.Errorf("%v:%v\n", e.startLine, e.startCol)
+	}
+	if rules := e.pluralRules(); len(rules) != 1 || rules[0].Value != "items" {
+		t.Errorf("%v\n", rules)
+	}
+	// Printing preserves the order
+	if actual := em.print(); actual != devStringsdict {
+		t.Errorf("%v\n", actual)
+	}
+}
+
+func TestParseStringsdictInvalid(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected string
+	}{
+		{
+			stringsdictHeader + "<array/>\n</plist>\n",
+			"a:4:1: stringsdict is not a dict",
+		},
+		{
+			stringsdictHeader + "<dict>\n\t<key>a</key>\n\t<string>a</string>\n</dict>\n</plist>\n",
+			"a:6:2: expected <dict>",
+		},
+		{
+			stringsdictHeader + "<dict>\n\t<key>a</key>\n\t<dict>\n\t\t<key>a</key>\n\t\t<integer>1</integer>\n\t</dict>\n</dict>\n</plist>\n",
+			"a:8:3: expected <string> or <dict>",
+		},
+	}
+	for _, c := range cases {
+		_, err := parseStringsdict(c.input, "a")
+		if err == nil || err.Error() != c.expected {
+			t.Errorf("%v\n", err)
+		}
+	}
+}
+
+func TestStringsdictSkeleton(t *testing.T) {
+	em, err := parseStringsdict(devStringsdict, "en.lproj/Localizable.stringsdict")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	out := stringsdictEntryMap{}.mergeDev(em, pluralCategoriesOfLproj("ru.lproj"))
+	expected := stringsdictHeader + `<dict>
+	<key>n_items</key>
+	<dict>
+		<key>NSStringLocalizedFormatKey</key>
+		<string>%#@items@</string>
+		<key>items</key>
+		<dict>
+			<key>NSStringFormatSpecTypeKey</key>
+			<string>NSStringPluralRuleType</string>
+			<key>NSStringFormatValueTypeKey</key>
+			<string>d</string>
+			<key>one</key>
+			<string>%d item</string>
+			<key>few</key>
+			<string>%d items</string>
+			<key>many</key>
+			<string>%d items</string>
+			<key>other</key>
+			<string>%d items</string>
+		</dict>
+	</dict>
+</dict>
+</plist>
+`
+	if actual := out.print(); actual != expected {
+		t.Errorf("%v\n", actual)
+	}
+}
+
+func TestStringsdictValidatePluralCategories(t *testing.T) {
+	em, err := parseStringsdict(devStringsdict, "ru.lproj/Localizable.stringsdict")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	err = em["n_items"].validatePluralCategories(pluralCategoriesOfLproj("ru.lproj"), "ru.lproj", "ru.lproj/Localizable.stringsdict")
+	expected := "ru.lproj/Localizable.stringsdict:9:3: `n_items` is missing plural category `few` of `items` for ru.lproj\n" +
+		"ru.lproj/Localizable.stringsdict:9:3: `n_items` is missing plural category `many` of `items` for ru.lproj"
+	if err == nil || err.Error() != expected {
+		t.Errorf("%v\n", err)
+	}
+	if err := em["n_items"].validatePluralCategories(pluralCategoriesOfLproj("en.lproj"), "en.lproj", "en.lproj/Localizable.stringsdict"); err != nil {
+		t.Errorf("%v\n", err)
+	}
+
+	// A skeleton has no position.
+	noOther := strings.Replace(devStringsdict, "\t\t\t<key>other</key>\n\t\t\t<string>%d items</string>\n", "", 1)
+	em, err = parseStringsdict(noOther, "en.lproj/Localizable.stringsdict")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	skeleton := em["n_items"].skeleton(pluralCategoriesOfLproj("ja.lproj"))
+	err = skeleton.validatePluralCategories(pluralCategoriesOfLproj("ja.lproj"), "ja.lproj", "ja.lproj/Localizable.stringsdict")
+	if err == nil || err.Error() != "ja.lproj/Localizable.stringsdict: `n_items` is missing plural category `other` of `items` for ja.lproj" {
+		t.Errorf("%v\n", err)
+	}
+}
+
+func TestStringsdictMergeCalls(t *testing.T) {
+	em, err := parseStringsdict(devStringsdict, "en.lproj/Localizable.stringsdict")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	// The format can be stored in a variable
+	// so the key is kept even without localizedStringWithFormat.
+	out := em.mergeCalls(map[string]routineCall{"n_items": routineCall{key: "n_items"}})
+	if _, ok := out["n_items"]; !ok {
+		t.Errorf("%v\n", out)
+	}
+	out = em.mergeCalls(map[string]routineCall{"other": routineCall{key: "other"}})
+	if len(out) != 0 {
+		t.Errorf("%v\n", out)
+	}
+}
+
+func TestStringsdictPrintPreservesOrder(t *testing.T) {
+	input := stringsdictHeader + `<dict>
+	<key>b</key>
+	<dict>
+		<key>items</key>
+		<dict>
+			<key>other</key>
+			<string>%d items</string>
+			<key>NSStringFormatSpecTypeKey</key>
+			<string>NSStringPluralRuleType</string>
+		</dict>
+		<key>NSStringLocalizedFormatKey</key>
+		<string>%#@items@</string>
+	</dict>
+	<key>a</key>
+	<dict/>
+</dict>
+</plist>
+`
+	em, err := parseStringsdict(input, "a")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if actual := em.print(); actual != input {
+		t.Errorf("%v\n", actual)
+	}
+}
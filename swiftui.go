@@ -0,0 +1,79 @@
+package main
+
+// swiftUILocalizables are the SwiftUI initializers and modifiers
+// whose first argument is a LocalizedStringKey.
+var swiftUILocalizables = map[string]bool{
+	"Button":             true,
+	"ColorPicker":        true,
+	"DatePicker":         true,
+	"Label":              true,
+	"Link":               true,
+	"LocalizedStringKey": true,
+	"Menu":               true,
+	"NavigationLink":     true,
+	"Picker":             true,
+	"ProgressView":       true,
+	"SecureField":        true,
+	"Section":            true,
+	"Stepper":            true,
+	"Text":               true,
+	"TextField":          true,
+	"Toggle":             true,
+	"navigationTitle":    true,
+}
+
+func isSwiftUILocalizable(name string) bool {
+	return swiftUILocalizables[name]
+}
+
+// parseSwiftUI parses
+// Text(key), Text(key, tableName: table, bundle: bundle, comment: comment),
+// Button(key) { ... }, Label(key, systemImage: name), etc.
+// It returns false if the first argument is not a string literal,
+// such as Text(verbatim: s) or Text(s).
+func (p *routineCallParser) parseSwiftUI() (rc routineCall, ok bool) {
+	if token := p.nextNonSpace(); token.Type != itemParenLeft {
+		p.backup()
+		return rc, false
+	}
+	if token := p.nextNonSpace(); !isLocalizationValue(token) {
+		p.backup()
+		return rc, false
+	}
+	p.backup()
+	rc.key = p.parseLocalizationValue()
+
+	// Only tableName:, bundle: and comment: are parsed.
+	// Other arguments like systemImage: or destination:
+	// are left to the caller because they may contain
+	// other localizable views.
+	for {
+		token := p.nextNonSpace()
+		if token.Type == itemParenRight {
+			return rc, true
+		}
+		if token.Type != itemComma {
+			p.backup()
+			return rc, true
+		}
+		label := p.nextNonSpace()
+		if label.Type != itemIdentifier {
+			p.backup()
+			return rc, true
+		}
+		switch label.Value {
+		case "tableName":
+			p.expect(itemColon)
+			rc.table = p.parseString()
+		case "bundle":
+			p.expect(itemColon)
+			p.skipExpression()
+		case "comment":
+			p.expect(itemColon)
+			rc.comment = p.parseString()
+		default:
+			p.backup()
+			return rc, true
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSwiftUI(t *testing.T) {
+	routineName := "NSLocalizedString"
+	input := `
+struct ContentView: View {
+	var body: some View {
+		Text("Welcome")
+		Text("Hello, \(name)!", tableName: "Greeting", comment: "greeting")
+		Text(verbatim: "v")
+		Text(name)
+		Button("Save") { save() }
+		Label("Inbox", systemImage: "tray")
+		NavigationLink("Go", destination: Text("Detail"))
+		Text("\(distance, specifier: "%.1f") km")
+		let key = LocalizedStringKey("key")
+	}
+}
+`
+	expected := routineCallSlice{
+		routineCall{
+			filepath:  ".swift",
+			startLine: 4,
+			startCol:  3,
+			endLine:   4,
+			endCol:    18,
+			key:       "Welcome",
+		},
+		routineCall{
+			filepath:  ".swift",
+			startLine: 5,
+			startCol:  3,
+			endLine:   5,
+			endCol:    70,
+			key:       "Hello, %@!",
+			comment:   "greeting",
+			table:     "Greeting",
+		},
+		routineCall{
+			filepath:  ".swift",
+			startLine: 8,
+			startCol:  3,
+			endLine:   8,
+			endCol:    17,
+			key:       "Save",
+		},
+		routineCall{
+			filepath:  ".swift",
+			startLine: 9,
+			startCol:  3,
+			endLine:   9,
+			endCol:    17,
+			key:       "Inbox",
+		},
+		routineCall{
+			filepath:  ".swift",
+			startLine: 10,
+			startCol:  3,
+			endLine:   10,
+			endCol:    23,
+			key:       "Go",
+		},
+		routineCall{
+			filepath:  ".swift",
+			startLine: 10,
+			startCol:  37,
+			endLine:   10,
+			endCol:    51,
+			key:       "Detail",
+		},
+		routineCall{
+			filepath:  ".swift",
+			startLine: 11,
+			startCol:  3,
+			endLine:   11,
+			endCol:    44,
+			key:       "%.1f km",
+		},
+		routineCall{
+			filepath:  ".swift",
+			startLine: 12,
+			startCol:  13,
+			endLine:   12,
+			endCol:    38,
+			key:       "key",
+		},
+	}
+	actual, err := parseRoutineCalls(input, routineName, ".swift", true)
+	if err != nil {
+		t.Errorf("%v\n", err)
+	} else if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("%v\n", actual)
+	}
+
+	// SwiftUI is opt-in.
+	actual, err = parseRoutineCalls(input, routineName, ".swift", false)
+	if err != nil || len(actual) != 0 {
+		t.Fail()
+	}
+
+	// NSLocalizedString does not support interpolation.
+	_, err = parseRoutineCalls(`NSLocalizedString("\(a)", comment: "")`, routineName, ".swift", true)
+	if err == nil || err.Error() != ".swift:1:19: unexpected token `<interpolated-string>`" {
+		t.Errorf("%v\n", err)
+	}
+}
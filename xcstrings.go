@@ -0,0 +1,351 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/iawaknahc/gogenstrings/errors"
+	"github.com/iawaknahc/gogenstrings/linecol"
+)
+
+const dotXCStringsExt = ".xcstrings"
+
+// Extraction states of String Catalogs.
+// A key extracted from source code has no extraction state.
+const (
+	extractionStateManual             = "manual"
+	extractionStateStale              = "stale"
+	extractionStateExtractedWithValue = "extracted_with_value"
+)
+
+// States of stringUnit.
+const (
+	stringUnitStateNew        = "new"
+	stringUnitStateTranslated = "translated"
+)
+
+// stringCatalog is .xcstrings.
+// Fields are in alphabetical order as Xcode writes them.
+// Every level keeps the fields it does not know in extra
+// so that they survive a rewrite.
+type stringCatalog struct {
+	SourceLanguage string                        `json:"sourceLanguage"`
+	Strings        map[string]stringCatalogEntry `json:"strings"`
+	Version        string                        `json:"version"`
+
+	extra map[string]json.RawMessage
+}
+
+type stringCatalogEntry struct {
+	Comment         string                               `json:"comment,omitempty"`
+	ExtractionState string                               `json:"extractionState,omitempty"`
+	Localizations   map[string]stringCatalogLocalization `json:"localizations,omitempty"`
+	ShouldTranslate *bool                                `json:"shouldTranslate,omitempty"`
+
+	extra map[string]json.RawMessage
+}
+
+type stringCatalogLocalization struct {
+	StringUnit    *stringCatalogStringUnit             `json:"stringUnit,omitempty"`
+	Substitutions map[string]stringCatalogSubstitution `json:"substitutions,omitempty"`
+	Variations    stringCatalogVariations              `json:"variations,omitempty"`
+
+	extra map[string]json.RawMessage
+}
+
+type stringCatalogStringUnit struct {
+	State string `json:"state"`
+	Value string `json:"value"`
+
+	extra map[string]json.RawMessage
+}
+
+type stringCatalogSubstitution struct {
+	ArgNum          int                     `json:"argNum,omitempty"`
+	FormatSpecifier string                  `json:"formatSpecifier,omitempty"`
+	Variations      stringCatalogVariations `json:"variations,omitempty"`
+
+	extra map[string]json.RawMessage
+}
+
+// The aliases have no methods so that
+// they are encoded and decoded as usual.
+type (
+	stringCatalogAlias             stringCatalog
+	stringCatalogEntryAlias        stringCatalogEntry
+	stringCatalogLocalizationAlias stringCatalogLocalization
+	stringCatalogStringUnitAlias   stringCatalogStringUnit
+	stringCatalogSubstitutionAlias stringCatalogSubstitution
+)
+
+func (c stringCatalog) MarshalJSON() ([]byte, error) {
+	return marshalWithExtra(stringCatalogAlias(c), c.extra)
+}
+
+func (c *stringCatalog) UnmarshalJSON(b []byte) error {
+	return unmarshalWithExtra(b, (*stringCatalogAlias)(c), &c.extra)
+}
+
+func (e stringCatalogEntry) MarshalJSON() ([]byte, error) {
+	return marshalWithExtra(stringCatalogEntryAlias(e), e.extra)
+}
+
+func (e *stringCatalogEntry) UnmarshalJSON(b []byte) error {
+	return unmarshalWithExtra(b, (*stringCatalogEntryAlias)(e), &e.extra)
+}
+
+func (l stringCatalogLocalization) MarshalJSON() ([]byte, error) {
+	return marshalWithExtra(stringCatalogLocalizationAlias(l), l.extra)
+}
+
+func (l *stringCatalogLocalization) UnmarshalJSON(b []byte) error {
+	return unmarshalWithExtra(b, (*stringCatalogLocalizationAlias)(l), &l.extra)
+}
+
+func (u stringCatalogStringUnit) MarshalJSON() ([]byte, error) {
+	return marshalWithExtra(stringCatalogStringUnitAlias(u), u.extra)
+}
+
+func (u *stringCatalogStringUnit) UnmarshalJSON(b []byte) error {
+	return unmarshalWithExtra(b, (*stringCatalogStringUnitAlias)(u), &u.extra)
+}
+
+func (s stringCatalogSubstitution) MarshalJSON() ([]byte, error) {
+	return marshalWithExtra(stringCatalogSubstitutionAlias(s), s.extra)
+}
+
+func (s *stringCatalogSubstitution) UnmarshalJSON(b []byte) error {
+	return unmarshalWithExtra(b, (*stringCatalogSubstitutionAlias)(s), &s.extra)
+}
+
+// jsonFieldNames returns the JSON names of the fields of struct v.
+func jsonFieldNames(v interface{}) map[string]bool {
+	out := make(map[string]bool)
+	t := reflect.TypeOf(v)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	for i := 0; i < t.NumField(); i++ {
+		tag := t.Field(i).Tag.Get("json")
+		if name := strings.Split(tag, ",")[0]; name != "" {
+			out[name] = true
+		}
+	}
+	return out
+}
+
+// unmarshalWithExtra decodes b into v
+// and the fields v does not know into extra.
+func unmarshalWithExtra(b []byte, v interface{}, extra *map[string]json.RawMessage) error {
+	if err := json.Unmarshal(b, v); err != nil {
+		return err
+	}
+	all := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(b, &all); err != nil {
+		return err
+	}
+	known := jsonFieldNames(v)
+	for name, raw := range all {
+		if known[name] {
+			continue
+		}
+		if *extra == nil {
+			*extra = make(map[string]json.RawMessage)
+		}
+		(*extra)[name] = raw
+	}
+	return nil
+}
+
+// marshalWithExtra encodes v with extra.
+// Keys are sorted as Xcode does.
+func marshalWithExtra(v interface{}, extra map[string]json.RawMessage) ([]byte, error) {
+	b, err := marshalJSON(v)
+	if err != nil || len(extra) <= 0 {
+		return b, err
+	}
+	all := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(b, &all); err != nil {
+		return nil, err
+	}
+	for name, raw := range extra {
+		all[name] = raw
+	}
+	return marshalJSON(all)
+}
+
+// marshalJSON is json.Marshal without escaping HTML.
+func marshalJSON(v interface{}) ([]byte, error) {
+	buf := bytes.Buffer{}
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSpace(buf.Bytes()), nil
+}
+
+// stringCatalogVariations is keyed by the kind of variation,
+// e.g. "plural" or "device", then the case, e.g. "one".
+type stringCatalogVariations map[string]map[string]stringCatalogLocalization
+
+func newStringCatalog(sourceLanguage string) stringCatalog {
+	return stringCatalog{
+		SourceLanguage: sourceLanguage,
+		Strings:        make(map[string]stringCatalogEntry),
+		Version:        "1.0",
+	}
+}
+
+func parseStringCatalog(src, filepath string) (stringCatalog, error) {
+	c := stringCatalog{}
+	err := json.Unmarshal([]byte(src), &c)
+	if err != nil {
+		offset := -1
+		msg := err.Error()
+		switch e := err.(type) {
+		case *json.SyntaxError:
+			offset = int(e.Offset)
+		case *json.UnmarshalTypeError:
+			offset = int(e.Offset)
+			// Do not leak the Go types.
+			msg = fmt.Sprintf("unexpected %v in `%v`", e.Value, e.Field)
+		}
+		lineColer := linecol.NewLineColer(src)
+		// The offset is after the offending byte.
+		line, col := lineColer.LineCol(offset - 1)
+		return c, errors.FileLineCol(filepath, line, col, msg)
+	}
+	if c.Strings == nil {
+		c.Strings = make(map[string]stringCatalogEntry)
+	}
+	return c, nil
+}
+
+// newStringCatalogEntry makes the entry of the call.
+// The default value is the stringUnit of the source language.
+func newStringCatalogEntry(rc routineCall, sourceLanguage string) stringCatalogEntry {
+	e := stringCatalogEntry{
+		Comment: rc.comment,
+	}
+	if rc.value != "" && rc.value != rc.key {
+		e.ExtractionState = extractionStateExtractedWithValue
+		e.Localizations = map[string]stringCatalogLocalization{
+			sourceLanguage: {
+				StringUnit: &stringCatalogStringUnit{
+					State: stringUnitStateNew,
+					Value: rc.value,
+				},
+			},
+		}
+	}
+	return e
+}
+
+// mergeCall updates the comment.
+// Localizations are untouched.
+func (e stringCatalogEntry) mergeCall(rc routineCall) stringCatalogEntry {
+	if rc.comment != "" {
+		e.Comment = rc.comment
+	}
+	if e.ExtractionState == extractionStateStale {
+		e.ExtractionState = ""
+	}
+	return e
+}
+
+// mergeCalls is like entryMap.mergeCalls
+// but keys not in use are kept as stale.
+// Manual keys are never stale.
+func (c stringCatalog) mergeCalls(calls map[string]routineCall) stringCatalog {
+	out := newStringCatalog(c.SourceLanguage)
+	out.Version = c.Version
+	out.extra = c.extra
+	for key, e := range c.Strings {
+		if call, ok := calls[key]; ok {
+			out.Strings[key] = e.mergeCall(call)
+			continue
+		}
+		if e.ExtractionState != extractionStateManual {
+			e.ExtractionState = extractionStateStale
+		}
+		out.Strings[key] = e
+	}
+	for key, call := range calls {
+		if _, ok := out.Strings[key]; !ok {
+			out.Strings[key] = newStringCatalogEntry(call, c.SourceLanguage)
+		}
+	}
+	return out
+}
+
+// print prints in the format of Xcode,
+// i.e. keys are sorted, indented with 2 spaces
+// and separated from values by " : ".
+func (c stringCatalog) print() string {
+	b, err := marshalJSON(c)
+	if err != nil {
+		// stringCatalog contains only strings, ints, bools
+		// and the valid JSON it has read.
+		panic(fmt.Errorf("unreachable"))
+	}
+	return indentStringCatalog(b) + "\n"
+}
+
+// indentStringCatalog indents compact JSON.
+// Empty objects are printed as "{\n\n}".
+func indentStringCatalog(compact []byte) string {
+	buf := bytes.Buffer{}
+	depth := 0
+	newline := func() {
+		buf.WriteByte('\n')
+		for i := 0; i < depth; i++ {
+			buf.WriteString("  ")
+		}
+	}
+	inString := false
+	for i := 0; i < len(compact); i++ {
+		c := compact[i]
+		if inString {
+			buf.WriteByte(c)
+			if c == '\\' {
+				i++
+				buf.WriteByte(compact[i])
+			} else if c == '"' {
+				inString = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			inString = true
+			buf.WriteByte(c)
+		case '{', '[':
+			buf.WriteByte(c)
+			if i+1 < len(compact) && (compact[i+1] == '}' || compact[i+1] == ']') {
+				buf.WriteByte('\n')
+				newline()
+				buf.WriteByte(compact[i+1])
+				i++
+				continue
+			}
+			depth++
+			newline()
+		case '}', ']':
+			depth--
+			newline()
+			buf.WriteByte(c)
+		case ':':
+			buf.WriteString(" : ")
+		case ',':
+			buf.WriteByte(c)
+			newline()
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	return buf.String()
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const stringCatalogSrc = `{
+  "sourceLanguage" : "en",
+  "strings" : {
+    "a" : {
+      "comment" : "A",
+      "localizations" : {
+        "ja" : {
+          "stringUnit" : {
+            "state" : "needs_review",
+            "value" : "エー"
+          }
+        }
+      }
+    },
+    "b" : {
+
+    },
+    "c" : {
+      "extractionState" : "manual",
+      "localizations" : {
+        "en" : {
+          "variations" : {
+            "plural" : {
+              "one" : {
+                "stringUnit" : {
+                  "state" : "translated",
+                  "value" : "%lld item"
+                }
+              },
+              "other" : {
+                "stringUnit" : {
+                  "state" : "translated",
+                  "value" : "%lld items"
+                }
+              }
+            }
+          }
+        }
+      }
+    }
+  },
+  "version" : "1.0"
+}
+`
+
+func TestParsePrintStringCatalog(t *testing.T) {
+	c, err := parseStringCatalog(stringCatalogSrc, "Localizable.xcstrings")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if c.SourceLanguage != "en" || len(c.Strings) != 3 {
+		t.Errorf("%+v\n", c)
+	}
+	if actual := c.print(); actual != stringCatalogSrc {
+		t.Errorf("%v\n", actual)
+	}
+
+	empty := newStringCatalog("en")
+	expected := "{\n  \"sourceLanguage\" : \"en\",\n  \"strings\" : {\n\n  },\n  \"version\" : \"1.0\"\n}\n"
+	if actual := empty.print(); actual != expected {
+		t.Errorf("%q\n", actual)
+	}
+
+	_, err = parseStringCatalog("{\n  \"strings\" : []\n}", "Localizable.xcstrings")
+	if err == nil || err.Error() != "Localizable.xcstrings:2:15: unexpected array in `strings`" {
+		t.Errorf("%v\n", err)
+	}
+}
+
+// xcodeStringCatalogSrc has fields which stringCatalog does not model.
+const xcodeStringCatalogSrc = `{
+  "sourceLanguage" : "en",
+  "strings" : {
+    "%lld files in %@" : {
+      "comment" : "Files",
+      "isCommentAutoGenerated" : true,
+      "localizations" : {
+        "en" : {
+          "stringUnit" : {
+            "state" : "translated",
+            "value" : "%#@files@ in %@"
+          },
+          "substitutions" : {
+            "files" : {
+              "argNum" : 1,
+              "formatSpecifier" : "lld",
+              "futureField" : [
+                1,
+                2
+              ],
+              "variations" : {
+                "plural" : {
+                  "other" : {
+                    "stringUnit" : {
+                      "state" : "translated",
+                      "value" : "%arg files"
+                    }
+                  }
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "Tap <b>here</b> & go" : {
+      "extractionState" : "migrated",
+      "localizations" : {
+        "ja" : {
+          "stringUnit" : {
+            "isReviewed" : false,
+            "state" : "translated",
+            "value" : "ここ & タップ"
+          },
+          "variations" : {
+            "device" : {
+              "mac" : {
+                "stringUnit" : {
+                  "state" : "translated",
+                  "value" : "クリック"
+                }
+              }
+            }
+          }
+        }
+      }
+    }
+  },
+  "version" : "1.0",
+  "x-custom" : {
+    "nested" : null
+  }
+}
+`
+
+func TestPrintStringCatalogRoundTrip(t *testing.T) {
+	c, err := parseStringCatalog(xcodeStringCatalogSrc, "Localizable.xcstrings")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if actual := c.print(); actual != xcodeStringCatalogSrc {
+		t.Errorf("%v\n", actual)
+	}
+	// Unknown fields survive a merge.
+	out := c.mergeCalls(map[string]routineCall{
+		"%lld files in %@": routineCall{key: "%lld files in %@", comment: "Files"},
+	})
+	if actual := out.print(); !strings.Contains(actual, `"isCommentAutoGenerated" : true`) || !strings.Contains(actual, `"x-custom"`) {
+		t.Errorf("%v\n", actual)
+	}
+}
+
+func TestStringCatalogMergeCalls(t *testing.T) {
+	c, err := parseStringCatalog(stringCatalogSrc, "Localizable.xcstrings")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	out := c.mergeCalls(map[string]routineCall{
+		"a": routineCall{key: "a", comment: "AA"},
+		"d": routineCall{key: "d", value: "D"},
+	})
+	a := out.Strings["a"]
+	if a.Comment != "AA" || a.ExtractionState != "" || a.Localizations["ja"].StringUnit.State != "needs_review" {
+		t.Errorf("%+v\n", a)
+	}
+	if b := out.Strings["b"]; b.ExtractionState != extractionStateStale {
+		t.Errorf("%+v\n", b)
+	}
+	if c := out.Strings["c"]; c.ExtractionState != extractionStateManual {
+		t.Errorf("%+v\n", c)
+	}
+	d := out.Strings["d"]
+	if d.ExtractionState != extractionStateExtractedWithValue || d.Localizations["en"].StringUnit.Value != "D" {
+		t.Errorf("%+v\n", d)
+	}
+
+	// A stale key in use is no longer stale.
+	out = out.mergeCalls(map[string]routineCall{
+		"b": routineCall{key: "b"},
+	})
+	if b := out.Strings["b"]; b.ExtractionState != "" {
+		t.Errorf("%+v\n", b)
+	}
+}
+
+func TestStringCatalog(t *testing.T) {
+	root, err := ioutil.TempDir("", "gogenstrings")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	defer os.RemoveAll(root)
+
+	// No lproj is required.
+	writeFiles(t, root, map[string]string{
+		"Localizable.xcstrings": stringCatalogSrc,
+		"A.swift":               `NSLocalizedString("a", comment: "A")`,
+	})
+	catalogPath := filepath.Join(root, "Localizable.xcstrings")
+
+	ctx := newGenstringsContext(genstringsOptions{
+		rootPath:    root,
+		devlang:     "en",
+		routineName: "NSLocalizedString",
+	})
+	if err := ctx.genstrings(); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	content, err := readFile(catalogPath)
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	c, err := parseStringCatalog(content, catalogPath)
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if c.Strings["b"].ExtractionState != extractionStateStale {
+		t.Errorf("%v\n", content)
+	}
+
+	// Another table still needs the development lproj.
+	writeFiles(t, root, map[string]string{
+		"B.swift": `NSLocalizedString("b", tableName: "Other", comment: "")`,
+	})
+	ctx = newGenstringsContext(genstringsOptions{
+		rootPath:    root,
+		devlang:     "en",
+		routineName: "NSLocalizedString",
+	})
+	err = ctx.genstrings()
+	if err == nil || err.Error() != filepath.Join(root, "en.lproj")+": directory not found" {
+		t.Errorf("%v\n", err)
+	}
+}
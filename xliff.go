@@ -0,0 +1,414 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/iawaknahc/gogenstrings/errors"
+	"github.com/iawaknahc/gogenstrings/linecol"
+)
+
+const (
+	dotXLIFFExt  = ".xliff"
+	xliffVersion = "1.2"
+
+	xliffStateNew        = "new"
+	xliffStateTranslated = "translated"
+)
+
+const ruleObsoleteKey = "obsolete-key"
+
+type xliffDocument struct {
+	XMLName xml.Name    `xml:"urn:oasis:names:tc:xliff:document:1.2 xliff"`
+	Version string      `xml:"version,attr"`
+	Files   []xliffFile `xml:"file"`
+}
+
+type xliffFile struct {
+	Original       string      `xml:"original,attr"`
+	SourceLanguage string      `xml:"source-language,attr"`
+	TargetLanguage string      `xml:"target-language,attr"`
+	Datatype       string      `xml:"datatype,attr"`
+	Header         xliffHeader `xml:"header"`
+	Body           xliffBody   `xml:"body"`
+
+	// The position of <file> in the imported file
+	filepath  string
+	startLine int
+	startCol  int
+}
+
+type xliffHeader struct {
+	Tool xliffTool `xml:"tool"`
+}
+
+type xliffTool struct {
+	ID   string `xml:"tool-id,attr"`
+	Name string `xml:"tool-name,attr"`
+}
+
+type xliffBody struct {
+	TransUnits []xliffTransUnit `xml:"trans-unit"`
+}
+
+type xliffTransUnit struct {
+	ID     string       `xml:"id,attr"`
+	Source string       `xml:"source"`
+	Target *xliffTarget `xml:"target"`
+	Note   string       `xml:"note,omitempty"`
+
+	// The position of <trans-unit> in the imported file
+	startLine int
+	startCol  int
+}
+
+type xliffTarget struct {
+	State string `xml:"state,attr,omitempty"`
+	Value string `xml:",chardata"`
+}
+
+// languageOfLproj returns the language of lproj,
+// e.g. "pt-BR" of "path/to/pt-BR.lproj".
+func languageOfLproj(lproj string) string {
+	return strings.TrimSuffix(filepath.Base(lproj), ".lproj")
+}
+
+// newXLIFFFile makes a <file> of the translation em
+// of the development language dev.
+// Keys not in em are in the state new.
+// So are keys whose value is still the development language,
+// which mergeDev copies into every lproj.
+func newXLIFFFile(original, sourceLanguage, targetLanguage string, dev, em entryMap) xliffFile {
+	file := xliffFile{
+		Original:       original,
+		SourceLanguage: sourceLanguage,
+		TargetLanguage: targetLanguage,
+		Datatype:       "plaintext",
+		Header: xliffHeader{
+			Tool: xliffTool{
+				ID:   toolName,
+				Name: toolName,
+			},
+		},
+	}
+	for _, devEntry := range dev.toEntries().sort() {
+		unit := xliffTransUnit{
+			ID:     devEntry.key,
+			Source: devEntry.value,
+			Note:   strings.TrimSpace(devEntry.comment),
+		}
+		// Like mergeDev, an untranslated key takes the value of
+		// the development language.
+		unit.Target = &xliffTarget{
+			State: xliffStateNew,
+			Value: devEntry.value,
+		}
+		if e, ok := em[devEntry.key]; ok && e.value != devEntry.value {
+			unit.Target.State = xliffStateTranslated
+			unit.Target.Value = e.value
+		}
+		file.Body.TransUnits = append(file.Body.TransUnits, unit)
+	}
+	return file
+}
+
+func (p xliffDocument) print() (string, error) {
+	b, err := xml.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return xml.Header + string(b) + "\n", nil
+}
+
+type xliffParser struct {
+	decoder   *xml.Decoder
+	offset    int
+	filepath  string
+	lineColer linecol.LineColer
+}
+
+func (p *xliffParser) nextToken() xml.Token {
+	p.offset = int(p.decoder.InputOffset())
+	token, err := p.decoder.Token()
+	if err != nil {
+		if err == io.EOF {
+			return nil
+		}
+		line, col := p.lineColer.LineCol(p.offset)
+		if syntaxErr, ok := err.(*xml.SyntaxError); ok {
+			panic(errors.FileLineCol(p.filepath, line, col, syntaxErr.Msg))
+		}
+		panic(errors.FileLineCol(p.filepath, line, col, err.Error()))
+	}
+	return token
+}
+
+func (p *xliffParser) recover(errp *error) {
+	if r := recover(); r != nil {
+		err, ok := r.(error)
+		if !ok {
+			panic("panicked without error")
+		}
+		*errp = err
+	}
+}
+
+func (p *xliffParser) decodeElement(v interface{}, start xml.StartElement) {
+	if err := p.decoder.DecodeElement(v, &start); err != nil {
+		line, col := p.lineColer.LineCol(p.offset)
+		panic(errors.FileLineCol(p.filepath, line, col, err.Error()))
+	}
+}
+
+func (p *xliffParser) parseFile(start xml.StartElement) xliffFile {
+	file := xliffFile{filepath: p.filepath}
+	file.startLine, file.startCol = p.lineColer.LineCol(p.offset)
+	for _, attr := range start.Attr {
+		switch attr.Name.Local {
+		case "original":
+			file.Original = attr.Value
+		case "source-language":
+			file.SourceLanguage = attr.Value
+		case "target-language":
+			file.TargetLanguage = attr.Value
+		case "datatype":
+			file.Datatype = attr.Value
+		}
+	}
+	for {
+		token := p.nextToken()
+		switch t := token.(type) {
+		case nil:
+			line, col := p.lineColer.LineCol(p.offset)
+			panic(errors.FileLineCol(p.filepath, line, col, "unexpected EOF; expected </file>"))
+		case xml.EndElement:
+			if t.Name.Local == "file" {
+				return file
+			}
+		case xml.StartElement:
+			if t.Name.Local == "trans-unit" {
+				unit := xliffTransUnit{}
+				unit.startLine, unit.startCol = p.lineColer.LineCol(p.offset)
+				p.decodeElement(&unit, t)
+				file.Body.TransUnits = append(file.Body.TransUnits, unit)
+			}
+		}
+	}
+}
+
+func (p *xliffParser) parse() []xliffFile {
+	files := []xliffFile{}
+	for {
+		token := p.nextToken()
+		if token == nil {
+			return files
+		}
+		if start, ok := token.(xml.StartElement); ok && start.Name.Local == "file" {
+			files = append(files, p.parseFile(start))
+		}
+	}
+}
+
+// parseXLIFF parses the <file> and <trans-unit> of XLIFF 1.2.
+// Other elements are ignored.
+func parseXLIFF(src, filepath string) (files []xliffFile, err error) {
+	p := &xliffParser{
+		decoder:   xml.NewDecoder(strings.NewReader(src)),
+		filepath:  filepath,
+		lineColer: linecol.NewLineColer(src),
+	}
+	defer p.recover(&err)
+	files = p.parse()
+	return
+}
+
+// exportXLIFF writes <lang>.xliff in outDir for every lproj
+// other than the development language.
+func (p *genstringsContext) exportXLIFF(outDir string) error {
+	if err := p.load(); err != nil {
+		return err
+	}
+
+	devLproj := p.devLproj
+	sourceLanguage := languageOfLproj(devLproj)
+	tables := []string{}
+	for table := range p.inEntryMap {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return err
+	}
+	for _, lproj := range p.lprojs {
+		if lproj == devLproj {
+			continue
+		}
+		targetLanguage := languageOfLproj(lproj)
+		doc := xliffDocument{Version: xliffVersion}
+		for _, table := range tables {
+			inEntryMap := p.inEntryMap[table]
+			doc.Files = append(doc.Files, newXLIFFFile(
+				path.Join(filepath.Base(devLproj), table+dotStringsExt),
+				sourceLanguage,
+				targetLanguage,
+				inEntryMap[devLproj],
+				inEntryMap[lproj],
+			))
+		}
+		if p.infoPlistPath != "" {
+			doc.Files = append(doc.Files, newXLIFFFile(
+				path.Join(filepath.Base(devLproj), infoPlistDotStrings),
+				sourceLanguage,
+				targetLanguage,
+				p.inInfoPlistEntryMap[devLproj],
+				p.inInfoPlistEntryMap[lproj],
+			))
+		}
+		content, err := doc.print()
+		if err != nil {
+			return err
+		}
+		targetPath := filepath.Join(outDir, targetLanguage+dotXLIFFExt)
+		if err := writeFile(targetPath, content, encodingUTF8); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// importXLIFF merges the targets in xliffPaths into
+// the output of genstrings and writes it.
+// Keys no longer exist are returned as obsolete.
+// Like genstrings, bad translations are written and then reported.
+func (p *genstringsContext) importXLIFF(xliffPaths []string) (obsolete error, err error) {
+	if err := p.load(); err != nil {
+		return nil, err
+	}
+	p.process()
+
+	files := []xliffFile{}
+	for _, xliffPath := range xliffPaths {
+		content, err := readFile(xliffPath)
+		if err != nil {
+			p.diagnostics.Add(err)
+			continue
+		}
+		fs, err := parseXLIFF(content, xliffPath)
+		if err != nil {
+			p.diagnostics.Add(err)
+			continue
+		}
+		files = append(files, fs...)
+	}
+	if err := p.diagnostics.Err(); err != nil {
+		return nil, err
+	}
+
+	warnings := errors.Diagnostics{}
+	for _, file := range files {
+		warnings.Add(p.importXLIFFFile(file))
+	}
+	if err := p.diagnostics.Err(); err != nil {
+		return nil, err
+	}
+
+	invalid := p.validateOutput()
+	if err := p.write(); err != nil {
+		return nil, err
+	}
+	return warnings.Err(), invalid
+}
+
+// importXLIFFFile merges file into outEntryMap.
+// Errors are collected in diagnostics while
+// obsolete keys are returned.
+func (p *genstringsContext) importXLIFFFile(file xliffFile) error {
+	lproj := ""
+	for _, l := range p.lprojs {
+		if languageOfLproj(l) == file.TargetLanguage {
+			lproj = l
+			break
+		}
+	}
+	if lproj == "" {
+		p.diagnostics.Add(errors.FileLineCol(
+			file.filepath,
+			file.startLine,
+			file.startCol,
+			fmt.Sprintf("directory not found: %v.lproj", file.TargetLanguage),
+		))
+		return nil
+	}
+	if lproj == p.devLproj {
+		p.diagnostics.Add(errors.FileLineCol(
+			file.filepath,
+			file.startLine,
+			file.startCol,
+			"cannot import the development language",
+		))
+		return nil
+	}
+
+	basename := path.Base(file.Original)
+	var outEntryMap map[string]entryMap
+	if basename == infoPlistDotStrings && p.infoPlistPath != "" {
+		outEntryMap = p.outInfoPlistEntryMap
+	} else {
+		outEntryMap = p.outEntryMap[strings.TrimSuffix(basename, dotStringsExt)]
+	}
+	if outEntryMap == nil {
+		return errors.FileLineCol(
+			file.filepath,
+			file.startLine,
+			file.startCol,
+			fmt.Sprintf("`%v` no longer exists", basename),
+		).WithRule(ruleObsoleteKey)
+	}
+
+	dev := outEntryMap[p.devLproj]
+	em := entryMap{}
+	for key, e := range outEntryMap[lproj] {
+		em[key] = e
+	}
+	errs := errors.List{}
+	for _, unit := range file.Body.TransUnits {
+		// Untranslated
+		if unit.Target == nil || unit.Target.State == xliffStateNew {
+			continue
+		}
+		devEntry, ok := dev[unit.ID]
+		if !ok {
+			errs = append(errs, errors.FileLineCol(
+				file.filepath,
+				unit.startLine,
+				unit.startCol,
+				fmt.Sprintf("`%v` no longer exists", unit.ID),
+			).WithRule(ruleObsoleteKey))
+			continue
+		}
+		e, ok := em[unit.ID]
+		if !ok {
+			e = devEntry
+		}
+		// The value comes from the trans-unit
+		// so diagnostics of it point there.
+		e.filepath = file.filepath
+		e.startLine = unit.startLine
+		e.startCol = unit.startCol
+		e.value = unit.Target.Value
+		em[unit.ID] = e
+	}
+	outEntryMap[lproj] = em.mergeDev(dev)
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
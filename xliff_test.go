@@ -0,0 +1,256 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseXLIFF(t *testing.T) {
+	src := `<?xml version="1.0" encoding="UTF-8"?>
+<xliff xmlns="urn:oasis:names:tc:xliff:document:1.2" version="1.2">
+  <file original="en.lproj/Localizable.strings" source-language="en" target-language="ja" datatype="plaintext">
+    <body>
+      <trans-unit id="a">
+        <source>a</source>
+        <target state="translated">エー</target>
+        <note>comment</note>
+      </trans-unit>
+      <trans-unit id="b">
+        <source>b</source>
+      </trans-unit>
+    </body>
+  </file>
+</xliff>
+`
+	files, err := parseXLIFF(src, "ja.xliff")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("%v\n", files)
+	}
+	file := files[0]
+	if file.Original != "en.lproj/Localizable.strings" || file.TargetLanguage != "ja" || file.startLine != 3 || file.startCol != 3 {
+		t.Errorf("%+v\n", file)
+	}
+	units := file.Body.TransUnits
+	if len(units) != 2 {
+		t.Fatalf("%+v\n", units)
+	}
+	if units[0].ID != "a" || units[0].Target == nil || units[0].Target.Value != "エー" || units[0].Note != "comment" || units[0].startLine != 5 || units[0].startCol != 7 {
+		t.Errorf("%+v\n", units[0])
+	}
+	if units[1].ID != "b" || units[1].Target != nil || units[1].startLine != 10 {
+		t.Errorf("%+v\n", units[1])
+	}
+
+	_, err = parseXLIFF("<xliff>\n<file></xliff>", "ja.xliff")
+	if err == nil || err.Error() != "ja.xliff:2:7: element <file> closed by </xliff>" {
+		t.Errorf("%v\n", err)
+	}
+}
+
+func TestExportImportXLIFF(t *testing.T) {
+	root, err := ioutil.TempDir("", "gogenstrings")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	defer os.RemoveAll(root)
+
+	writeFiles(t, root, map[string]string{
+		"en.lproj/Localizable.strings": "/* A */\n\"a\" = \"a\";\n\n/* B */\n\"b\" = \"b\";\n\n",
+		"ja.lproj/Localizable.strings": "\"a\" = \"エー\";\n",
+		"A.swift":                      `NSLocalizedString("a", comment: "A")` + "\n" + `NSLocalizedString("b", comment: "B")`,
+	})
+	outDir := filepath.Join(root, "xliff")
+
+	ctx := newGenstringsContext(genstringsOptions{
+		rootPath:    root,
+		devlang:     "en",
+		routineName: "NSLocalizedString",
+	})
+	if err := ctx.exportXLIFF(outDir); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	xliffPath := filepath.Join(outDir, "ja.xliff")
+	actual, err := readFile(xliffPath)
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<xliff xmlns="urn:oasis:names:tc:xliff:document:1.2" version="1.2">
+  <file original="en.lproj/Localizable.strings" source-language="en" target-language="ja" datatype="plaintext">
+    <header>
+      <tool tool-id="gogenstrings" tool-name="gogenstrings"></tool>
+    </header>
+    <body>
+      <trans-unit id="a">
+        <source>a</source>
+        <target state="translated">エー</target>
+        <note>A</note>
+      </trans-unit>
+      <trans-unit id="b">
+        <source>b</source>
+        <target state="new">b</target>
+        <note>B</note>
+      </trans-unit>
+    </body>
+  </file>
+</xliff>
+`
+	if actual != expected {
+		t.Errorf("%v\n", actual)
+	}
+
+	// Translate b and add c which does not exist.
+	writeFiles(t, root, map[string]string{
+		"xliff/ja.xliff": `<?xml version="1.0" encoding="UTF-8"?>
+<xliff xmlns="urn:oasis:names:tc:xliff:document:1.2" version="1.2">
+  <file original="en.lproj/Localizable.strings" source-language="en" target-language="ja" datatype="plaintext">
+    <body>
+      <trans-unit id="b">
+        <source>b</source>
+        <target state="translated">ビー</target>
+      </trans-unit>
+      <trans-unit id="c">
+        <source>c</source>
+        <target state="translated">シー</target>
+      </trans-unit>
+    </body>
+  </file>
+</xliff>
+`,
+	})
+	ctx = newGenstringsContext(genstringsOptions{
+		rootPath:    root,
+		devlang:     "en",
+		routineName: "NSLocalizedString",
+	})
+	obsolete, err := ctx.importXLIFF([]string{xliffPath})
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if obsolete == nil || obsolete.Error() != xliffPath+":9:7: `c` no longer exists" {
+		t.Errorf("%v\n", obsolete)
+	}
+	actual, err = readFile(filepath.Join(root, "ja.lproj/Localizable.strings"))
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	expected = "/* A */\n\"a\" = \"エー\";\n\n/* B */\n\"b\" = \"ビー\";\n\n"
+	if actual != expected {
+		t.Errorf("%q\n", actual)
+	}
+
+	// Unknown language
+	writeFiles(t, root, map[string]string{
+		"xliff/fr.xliff": `<xliff><file original="en.lproj/Localizable.strings" target-language="fr"></file></xliff>`,
+	})
+	ctx = newGenstringsContext(genstringsOptions{
+		rootPath:    root,
+		devlang:     "en",
+		routineName: "NSLocalizedString",
+	})
+	_, err = ctx.importXLIFF([]string{filepath.Join(outDir, "fr.xliff")})
+	if err == nil || err.Error() != filepath.Join(outDir, "fr.xliff")+":1:8: directory not found: fr.lproj" {
+		t.Errorf("%v\n", err)
+	}
+}
+
+func TestExportXLIFFAfterGenstrings(t *testing.T) {
+	root, err := ioutil.TempDir("", "gogenstrings")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	defer os.RemoveAll(root)
+
+	writeFiles(t, root, map[string]string{
+		"en.lproj/Localizable.strings": "",
+		"ja.lproj/Localizable.strings": "",
+		"A.swift":                      `NSLocalizedString("a", comment: "A")`,
+	})
+
+	// genstrings copies a into ja.lproj untranslated.
+	ctx := newGenstringsContext(genstringsOptions{
+		rootPath:    root,
+		devlang:     "en",
+		routineName: "NSLocalizedString",
+	})
+	if err := ctx.genstrings(); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+
+	outDir := filepath.Join(root, "xliff")
+	ctx = newGenstringsContext(genstringsOptions{
+		rootPath:    root,
+		devlang:     "en",
+		routineName: "NSLocalizedString",
+	})
+	if err := ctx.exportXLIFF(outDir); err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	files, err := parseXLIFF(mustReadFile(t, filepath.Join(outDir, "ja.xliff")), "ja.xliff")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	units := files[0].Body.TransUnits
+	if len(units) != 1 || units[0].Target == nil || units[0].Target.State != xliffStateNew {
+		t.Errorf("%+v\n", units)
+	}
+}
+
+func mustReadFile(t *testing.T, fullpath string) string {
+	content, err := readFile(fullpath)
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	return content
+}
+
+func TestImportXLIFFBadTranslation(t *testing.T) {
+	root, err := ioutil.TempDir("", "gogenstrings")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	defer os.RemoveAll(root)
+
+	writeFiles(t, root, map[string]string{
+		"en.lproj/Localizable.strings": "/* A */\n\"%d a\" = \"%d a\";\n\n",
+		"ja.lproj/Localizable.strings": "",
+		"A.swift":                      `NSLocalizedString("%d a", comment: "A")`,
+		"xliff/ja.xliff": `<?xml version="1.0" encoding="UTF-8"?>
+<xliff xmlns="urn:oasis:names:tc:xliff:document:1.2" version="1.2">
+  <file original="en.lproj/Localizable.strings" source-language="en" target-language="ja" datatype="plaintext">
+    <body>
+      <trans-unit id="%d a">
+        <source>%d a</source>
+        <target state="translated">%@ エー</target>
+      </trans-unit>
+    </body>
+  </file>
+</xliff>
+`,
+	})
+	xliffPath := filepath.Join(root, "xliff/ja.xliff")
+
+	// Like genstrings, the translation is written and then reported.
+	ctx := newGenstringsContext(genstringsOptions{
+		rootPath:    root,
+		devlang:     "en",
+		routineName: "NSLocalizedString",
+	})
+	_, err = ctx.importXLIFF([]string{xliffPath})
+	if err == nil || err.Error() != xliffPath+":5:7: `%d a` uses argument 1 as `%@` but en.lproj uses it as `%d`" {
+		t.Errorf("%v\n", err)
+	}
+	actual, err := readFile(filepath.Join(root, "ja.lproj/Localizable.strings"))
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	expected := "/* A */\n\"%d a\" = \"%@ エー\";\n\n"
+	if actual != expected {
+		t.Errorf("%q\n", actual)
+	}
+}
@@ -0,0 +1,145 @@
+package xmlplist
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const xmlPlistHeader = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+`
+
+const xmlPlistFooter = "</plist>\n"
+
+// dataLineLength is the maximum length of a line of base64 in <data>.
+const dataLineLength = 76
+
+var xmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+type xmlPlistPrinter struct {
+	buf bytes.Buffer
+}
+
+func (p *xmlPlistPrinter) indent(depth int) {
+	for i := 0; i < depth; i++ {
+		p.buf.WriteByte('\t')
+	}
+}
+
+func (p *xmlPlistPrinter) element(depth int, name, content string) {
+	p.indent(depth)
+	p.buf.WriteString("<" + name + ">" + content + "</" + name + ">\n")
+}
+
+func (p *xmlPlistPrinter) emptyElement(depth int, name string) {
+	p.indent(depth)
+	p.buf.WriteString("<" + name + "/>\n")
+}
+
+func formatReal(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "nan"
+	case math.IsInf(f, 1):
+		return "+infinity"
+	case math.IsInf(f, -1):
+		return "-infinity"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func (p *xmlPlistPrinter) printData(depth int, data []byte) {
+	p.indent(depth)
+	p.buf.WriteString("<data>\n")
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 0 {
+		n := dataLineLength
+		if n > len(encoded) {
+			n = len(encoded)
+		}
+		p.indent(depth)
+		p.buf.WriteString(encoded[:n] + "\n")
+		encoded = encoded[n:]
+	}
+	p.indent(depth)
+	p.buf.WriteString("</data>\n")
+}
+
+func (p *xmlPlistPrinter) printValue(depth int, v interface{}) error {
+	value, ok := v.(Value)
+	if !ok {
+		return fmt.Errorf("expected Value but got %T", v)
+	}
+	switch x := value.Value.(type) {
+	case string:
+		p.element(depth, "string", xmlEscaper.Replace(x))
+	case float64:
+		p.element(depth, "real", formatReal(x))
+	case int64:
+		p.element(depth, "integer", strconv.FormatInt(x, 10))
+	case bool:
+		if x {
+			p.emptyElement(depth, "true")
+		} else {
+			p.emptyElement(depth, "false")
+		}
+	case time.Time:
+		p.element(depth, "date", x.UTC().Format("2006-01-02T15:04:05Z"))
+	case []byte:
+		p.printData(depth, x)
+	case []interface{}:
+		if len(x) <= 0 {
+			p.emptyElement(depth, "array")
+			return nil
+		}
+		p.indent(depth)
+		p.buf.WriteString("<array>\n")
+		for _, item := range x {
+			if err := p.printValue(depth+1, item); err != nil {
+				return err
+			}
+		}
+		p.indent(depth)
+		p.buf.WriteString("</array>\n")
+	case Dict:
+		if x.Len() <= 0 {
+			p.emptyElement(depth, "dict")
+			return nil
+		}
+		p.indent(depth)
+		p.buf.WriteString("<dict>\n")
+		for _, keyValue := range x.Keys() {
+			key := keyValue.Value.(string)
+			value, _ := x.Get(key)
+			p.element(depth+1, "key", xmlEscaper.Replace(key))
+			if err := p.printValue(depth+1, value); err != nil {
+				return err
+			}
+		}
+		p.indent(depth)
+		p.buf.WriteString("</dict>\n")
+	default:
+		return fmt.Errorf("unsupported type %T", value.Value)
+	}
+	return nil
+}
+
+// PrintXMLPlist prints value in the format of Xcode.
+// The elements of <array> must be Value.
+// The keys of <dict> are printed in order.
+// Line and Col are ignored.
+func PrintXMLPlist(value Value) (string, error) {
+	p := xmlPlistPrinter{}
+	p.buf.WriteString(xmlPlistHeader)
+	if err := p.printValue(0, value); err != nil {
+		return "", err
+	}
+	p.buf.WriteString(xmlPlistFooter)
+	return p.buf.String(), nil
+}
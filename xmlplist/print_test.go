@@ -0,0 +1,114 @@
+package xmlplist
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPrintXMLPlist(t *testing.T) {
+	dict := NewDict()
+	set := func(key string, value interface{}) {
+		dict.Set(key, 0, 0, Value{Value: value})
+	}
+	set("a", "<&>")
+	set("b", []interface{}{
+		Value{Value: true},
+		Value{Value: false},
+		Value{Value: int64(-1)},
+		Value{Value: 1.5},
+		Value{Value: float64(2)},
+		Value{Value: math.Inf(-1)},
+	})
+	set("c", time.Date(2017, 12, 25, 0, 0, 0, 0, time.UTC))
+	set("d", []byte("Hello"))
+	set("e", []interface{}{})
+	set("f", NewDict())
+	value := Value{Value: dict}
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>a</key>
+	<string>&lt;&amp;&gt;</string>
+	<key>b</key>
+	<array>
+		<true/>
+		<false/>
+		<integer>-1</integer>
+		<real>1.5</real>
+		<real>2</real>
+		<real>-infinity</real>
+	</array>
+	<key>c</key>
+	<date>2017-12-25T00:00:00Z</date>
+	<key>d</key>
+	<data>
+	SGVsbG8=
+	</data>
+	<key>e</key>
+	<array/>
+	<key>f</key>
+	<dict/>
+</dict>
+</plist>
+`
+	actual, err := PrintXMLPlist(value)
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if actual != expected {
+		t.Errorf("%v\n", actual)
+	}
+
+	// Round trip
+	parsed, err := ParseXMLPlist(actual, "")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if !reflect.DeepEqual(parsed.Flatten(), value.Flatten()) {
+		t.Errorf("%v\n", parsed.Flatten())
+	}
+}
+
+func TestPrintXMLPlistData(t *testing.T) {
+	data := bytes.Repeat([]byte{0}, 60)
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<array>
+	<data>
+	AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA
+	AAAA
+	</data>
+</array>
+</plist>
+`
+	actual, err := PrintXMLPlist(Value{Value: []interface{}{Value{Value: data}}})
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if actual != expected {
+		t.Errorf("%v\n", actual)
+	}
+	parsed, err := ParseXMLPlist(actual, "")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if !reflect.DeepEqual(parsed.Flatten(), []interface{}{data}) {
+		t.Errorf("%v\n", parsed.Flatten())
+	}
+}
+
+func TestPrintXMLPlistInvalid(t *testing.T) {
+	_, err := PrintXMLPlist(Value{Value: []interface{}{"a"}})
+	if err == nil || err.Error() != "expected Value but got string" {
+		t.Errorf("%v\n", err)
+	}
+	_, err = PrintXMLPlist(Value{Value: 1})
+	if err == nil || err.Error() != "unsupported type int" {
+		t.Errorf("%v\n", err)
+	}
+}
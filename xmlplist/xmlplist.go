@@ -27,7 +27,7 @@ type Value struct {
 	// <date>    -> time.Time
 	// <data>    -> []byte
 	// <array>   -> []interface{}
-	// <dict>    -> map[string]interface{}
+	// <dict>    -> Dict
 	Value interface{}
 	// Line is the line number.
 	Line int
@@ -35,6 +35,52 @@ type Value struct {
 	Col int
 }
 
+// Dict represents a dict preserving key order.
+// The zero value is not safe to use. Use NewDict instead.
+type Dict struct {
+	// keys are the keys in order.
+	keys []Value
+	// values maps key to value.
+	values map[string]Value
+}
+
+// NewDict creates an empty Dict.
+func NewDict() Dict {
+	return Dict{
+		values: make(map[string]Value),
+	}
+}
+
+// Set sets the value of key at line and col.
+// A new key is appended to the keys.
+// Line and col are 0 if key is not read from a file.
+func (d *Dict) Set(key string, line, col int, value Value) {
+	if _, ok := d.values[key]; !ok {
+		d.keys = append(d.keys, makeXMLPlistValue(key, line, col))
+	}
+	d.values[key] = value
+}
+
+// Get returns the value of key.
+func (d Dict) Get(key string) (Value, bool) {
+	value, ok := d.values[key]
+	return value, ok
+}
+
+// Keys returns the keys in order.
+// The Value of a key is always a string.
+// The position of a key is the position of <key>.
+func (d Dict) Keys() []Value {
+	out := make([]Value, len(d.keys))
+	copy(out, d.keys)
+	return out
+}
+
+// Len returns the number of keys.
+func (d Dict) Len() int {
+	return len(d.keys)
+}
+
 func (v Value) String() string {
 	switch x := v.Value.(type) {
 	case string:
@@ -54,7 +100,7 @@ func (v Value) String() string {
 		return "<data>"
 	case []interface{}:
 		return "<array>"
-	case map[string]interface{}:
+	case Dict:
 		return "<dict>"
 	}
 	panic(fmt.Errorf("unreachable"))
@@ -81,10 +127,10 @@ func (v Value) Flatten() interface{} {
 			out[i] = value.(Value).Flatten()
 		}
 		return out
-	case map[string]interface{}:
-		out := make(map[string]interface{}, len(x))
-		for key, value := range x {
-			out[key] = value.(Value).Flatten()
+	case Dict:
+		out := make(map[string]interface{}, len(x.values))
+		for key, value := range x.values {
+			out[key] = value.Flatten()
 		}
 		return out
 	}
@@ -343,9 +389,10 @@ func (p *xmlPlistParser) parseData(line, col int) []byte {
 		switch v := token.(type) {
 		case xml.EndElement:
 			if v.Name.Local == "data" {
-				src := bytes.Trim(buf.Bytes(), "\r\n\t ")
+				// Long data is wrapped and indented.
+				src := bytes.Join(bytes.Fields(buf.Bytes()), nil)
 				dst := make([]byte, base64.StdEncoding.DecodedLen(len(src)))
-				_, err := base64.StdEncoding.Decode(dst, src)
+				n, err := base64.StdEncoding.Decode(dst, src)
 				if err != nil {
 					panic(errors.FileLineCol(
 						p.filepath,
@@ -354,7 +401,7 @@ func (p *xmlPlistParser) parseData(line, col int) []byte {
 						fmt.Sprintf("%v", err),
 					))
 				}
-				return dst
+				return dst[:n]
 			}
 			p.unexpected(token, makeEndElement("data"))
 		case xml.CharData:
@@ -386,8 +433,8 @@ func (p *xmlPlistParser) parseArray() (out []interface{}) {
 	}
 }
 
-func (p *xmlPlistParser) parseDict() map[string]interface{} {
-	out := make(map[string]interface{})
+func (p *xmlPlistParser) parseDict() Dict {
+	out := NewDict()
 	for {
 		token := p.nextNonSpace()
 		if token == nil {
@@ -407,7 +454,7 @@ func (p *xmlPlistParser) parseDict() map[string]interface{} {
 			key := p.parseString("key")
 			startElement := p.expectStartElement(true, anyPlistValue)
 			value := p.parseValue(startElement)
-			if _, ok := out[key]; ok {
+			if _, ok := out.Get(key); ok {
 				panic(errors.FileLineCol(
 					p.filepath,
 					line,
@@ -415,7 +462,7 @@ func (p *xmlPlistParser) parseDict() map[string]interface{} {
 					fmt.Sprintf("duplicated key `%v`", key),
 				))
 			}
-			out[key] = value
+			out.Set(key, line, col, value)
 		default:
 			p.unexpected(token, anyPlistValue)
 		}
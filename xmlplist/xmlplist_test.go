@@ -7,34 +7,37 @@ import (
 )
 
 func TestXMLPlistValueFlatten(t *testing.T) {
-	input := Value{
-		Value: map[string]interface{}{
-			"key1": Value{
-				Value: []interface{}{
-					Value{
-						Value: int64(-1),
-					},
-					Value{
-						Value: 1.5,
-					},
-					Value{
-						Value: "s",
-					},
-					Value{
-						Value: time.Date(2017, 12, 25, 0, 0, 0, 0, time.UTC),
-					},
-					Value{
-						Value: true,
-					},
-					Value{
-						Value: false,
-					},
-					Value{
-						Value: []byte{105, 191, 191},
-					},
+	dict := NewDict()
+	dict.Set(
+		"key1", 0, 0,
+		Value{
+			Value: []interface{}{
+				Value{
+					Value: int64(-1),
+				},
+				Value{
+					Value: 1.5,
+				},
+				Value{
+					Value: "s",
+				},
+				Value{
+					Value: time.Date(2017, 12, 25, 0, 0, 0, 0, time.UTC),
+				},
+				Value{
+					Value: true,
+				},
+				Value{
+					Value: false,
+				},
+				Value{
+					Value: []byte{105, 191, 191},
 				},
 			},
 		},
+	)
+	input := Value{
+		Value: dict,
 	}
 	actual := input.Flatten()
 	expected := map[string]interface{}{
@@ -82,6 +85,8 @@ func TestParseXMLPlist(t *testing.T) {
 		// data
 		{"<data>ab+/</data>", []byte{105, 191, 191}},
 		{"<data>\t\n ab+/\t\n </data>", []byte{105, 191, 191}},
+		{"<data>SGVsbG8=</data>", []byte("Hello")},
+		{"<data>\n\tSGVs\n\tbG8=\n\t</data>", []byte("Hello")},
 
 		// array
 		{"<array></array>", []interface{}{}},
@@ -111,3 +116,65 @@ func TestParseXMLPlist(t *testing.T) {
 		}
 	}
 }
+
+func TestParseXMLPlistDict(t *testing.T) {
+	input := `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>b</key>
+	<string>b</string>
+	<key>a</key>
+	<string>a</string>
+</dict>
+</plist>
+`
+	value, err := ParseXMLPlist(input, "")
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	dict := value.Value.(Dict)
+	expected := []Value{
+		{Value: "b", Line: 5, Col: 2},
+		{Value: "a", Line: 7, Col: 2},
+	}
+	if !reflect.DeepEqual(dict.Keys(), expected) {
+		t.Errorf("%v\n", dict.Keys())
+	}
+	if v, ok := dict.Get("a"); !ok || v != (Value{Value: "a", Line: 8, Col: 2}) {
+		t.Errorf("%v\n", v)
+	}
+
+	// Printing preserves the order
+	actual, err := PrintXMLPlist(value)
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	if actual != input {
+		t.Errorf("%v\n", actual)
+	}
+}
+
+func TestDictSet(t *testing.T) {
+	dict := NewDict()
+	dict.Set("a", 1, 2, Value{Value: "1"})
+	dict.Set("b", 0, 0, Value{Value: "2"})
+	// Setting again keeps the order and the position.
+	dict.Set("a", 3, 4, Value{Value: "3"})
+	expected := []Value{
+		{Value: "a", Line: 1, Col: 2},
+		{Value: "b"},
+	}
+	keys := dict.Keys()
+	if !reflect.DeepEqual(keys, expected) {
+		t.Errorf("%v\n", keys)
+	}
+	if v, ok := dict.Get("a"); !ok || v.Value != "3" {
+		t.Errorf("%v\n", v)
+	}
+	// The keys cannot be changed from outside.
+	keys[0] = Value{Value: "c"}
+	if dict.Keys()[0].Value != "a" {
+		t.Errorf("%v\n", dict.Keys())
+	}
+}